@@ -7,6 +7,7 @@ require (
 	github.com/gorilla/websocket v1.4.2
 	github.com/rs/zerolog v1.26.1
 	go.uber.org/ratelimit v0.2.0
+	golang.org/x/exp v0.0.0-20220722155223-a9213eeb770e
 )
 
 require github.com/andres-erbsen/clock v0.0.0-20160526145045-9e14626cd129 // indirect
@@ -0,0 +1,88 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultBatchConcurrency is the amount of concurrent requests
+// of GetJSONBatch when MarketData.BatchConcurrency is zero.
+const DefaultBatchConcurrency = 4
+
+// JSONRequest holds the arguments of a GetJSON call.
+type JSONRequest struct {
+	Path   string
+	Data   interface{}
+	Target interface{}
+}
+
+// JSONResult is the outcome of a JSONRequest.
+// On success, Request.Target holds the decoded response.
+type JSONResult struct {
+	Request JSONRequest
+	Err     error
+}
+
+func (m *MarketData) batchConcurrency() int {
+	if m.BatchConcurrency <= 0 {
+		return DefaultBatchConcurrency
+	}
+	return m.BatchConcurrency
+}
+
+// GetJSONBatch runs GetJSON for each request,
+// with at most BatchConcurrency requests in flight.
+// The requests share the Limiter and the IPBackOff,
+// so the batch slows down instead of causing more back-off.
+// Results are returned in the order of reqs, an error of one request
+// does not affect the others.
+// Requests which were not started before ctx is done return the context error.
+func (m *MarketData) GetJSONBatch(ctx context.Context, reqs []JSONRequest) []JSONResult {
+	results := make([]JSONResult, len(reqs))
+	sem := make(chan struct{}, m.batchConcurrency())
+
+	var wg sync.WaitGroup
+
+	for i, req := range reqs {
+		results[i].Request = req
+
+		select {
+		case <-ctx.Done():
+			results[i].Err = fmt.Errorf("binance: %w", ctx.Err())
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(res *JSONResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			res.Err = m.GetJSON(ctx, res.Request.Path, res.Request.Data, res.Request.Target)
+		}(&results[i])
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,103 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMarketData_GetJSONBatch(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+			return
+		}
+		w.Write([]byte(`{"serverTime":1}`))
+	}))
+	m.BatchConcurrency = 2
+
+	reqs := make([]JSONRequest, 7)
+	for i := range reqs {
+		reqs[i] = JSONRequest{Path: "/api/v3/time", Target: &ServerTimeResp{}}
+	}
+	reqs[3].Path = "/fail"
+
+	results := m.GetJSONBatch(testCTX, reqs)
+	if len(results) != len(reqs) {
+		t.Fatalf("MarketData.GetJSONBatch() = %d results, want %d", len(results), len(reqs))
+	}
+
+	for i, res := range results {
+		if i == 3 {
+			var re RequestError
+			if !errors.As(res.Err, &re) || re.ErrorCode != -1121 {
+				t.Errorf("result %d error = %v, want code -1121", i, res.Err)
+			}
+			continue
+		}
+		if res.Err != nil {
+			t.Errorf("result %d error = %v", i, res.Err)
+		}
+		if got := res.Request.Target.(*ServerTimeResp).ServerTime; got != 1 {
+			t.Errorf("result %d server time = %d, want 1", i, got)
+		}
+	}
+
+	if got := atomic.LoadInt32(&maxInFlight); got > 2 {
+		t.Errorf("MarketData.GetJSONBatch() concurrency = %d, want at most 2", got)
+	}
+}
+
+func TestMarketData_GetJSONBatch_canceled(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+
+	ctx, cancel := context.WithCancel(testCTX)
+	cancel()
+
+	results := m.GetJSONBatch(ctx, []JSONRequest{
+		{Path: "/api/v3/ping", Target: &PingResp{}},
+		{Path: "/api/v3/ping", Target: &PingResp{}},
+	})
+	for i, res := range results {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Errorf("result %d error = %v, want %v", i, res.Err, context.Canceled)
+		}
+	}
+}
@@ -19,6 +19,40 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 // Package binance provides the connection driver for the binance API.
 package binance
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+func init() {
+	driver.Register("binance", open)
+}
+
+var _ driver.Driver = (*Stream)(nil)
+
+// open is the driver.Constructor of binance.
+// The "region" option selects the Hosts: "global" (default) or "us".
+func open(cfg driver.Config) (driver.Driver, error) {
+	var hosts Hosts
+
+	switch region := cfg.Options["region"]; region {
+	case "", "global":
+		hosts = GlobalHosts
+	case "us":
+		hosts = USHosts
+	default:
+		return nil, fmt.Errorf("binance: unknown region %q", region)
+	}
+
+	s, err := NewStream(cfg.Context, WithHosts(hosts))
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 // Endpoint paths
 const (
 	EndpointWsBase   = "wss://stream.binance.com:9443"
@@ -34,3 +68,47 @@ const (
 	MethodWsSetProperty       = "SET_PROPERTY"
 	MethodWsGetProperty       = "GET_PROPERTY"
 )
+
+// Hosts is the set of REST API and websocket hosts of a binance region.
+type Hosts struct {
+	API    []string // REST API hosts, tried in order
+	WsBase string   // Websocket base endpoint
+}
+
+func (h Hosts) streamEndpoint() string {
+	return h.WsBase + "/stream"
+}
+
+// streamURL returns the combined stream endpoint,
+// which subscribes to streams on connect.
+func (h Hosts) streamURL(streams []string) string {
+	if len(streams) == 0 {
+		return h.streamEndpoint()
+	}
+	return h.streamEndpoint() + "?streams=" + strings.Join(streams, "/")
+}
+
+var (
+	// GlobalHosts of binance.com, used by default.
+	GlobalHosts = Hosts{
+		API: []string{
+			"api.binance.com",
+			"api1.binance.com",
+			"api2.binance.com",
+			"api3.binance.com",
+		},
+		WsBase: EndpointWsBase,
+	}
+
+	// USHosts of binance.us.
+	// Binance.US supports a subset of the binance.com symbols and streams.
+	// Some endpoints have different request weights or are not available at all,
+	// most notably the margin, futures and savings endpoints.
+	// Binance.US does not serve clients outside the US.
+	USHosts = Hosts{
+		API: []string{
+			"api.binance.us",
+		},
+		WsBase: "wss://stream.binance.us:9443",
+	}
+)
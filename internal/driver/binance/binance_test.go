@@ -24,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/muhlemmer/yatgo/internal/driver"
 	"github.com/rs/zerolog/log"
 )
 
@@ -53,3 +54,34 @@ func testMain(m *testing.M) int {
 func TestMain(m *testing.M) {
 	os.Exit(testMain(m))
 }
+
+func Test_open(t *testing.T) {
+	tests := []struct {
+		name    string
+		region  string
+		wantErr bool
+	}{
+		{"unknown region", "mars", true},
+		{"global", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(testCTX)
+			defer cancel()
+
+			got, err := driver.Open("binance", driver.Config{
+				Context: ctx,
+				Options: map[string]string{"region": tt.region},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("driver.Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			cancel()
+			got.(*Stream).wg.Wait()
+		})
+	}
+}
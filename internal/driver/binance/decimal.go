@@ -0,0 +1,199 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Decimal is an exact decimal number.
+// Binance sends prices and quantities as strings, to preserve precision.
+// Decimal keeps that precision, where float64 would round large or long values.
+//
+// A Decimal is backed by a big.Rat and remembers the amount of fractional digits,
+// so that String returns the exact representation it was parsed from.
+// Decimals are immutable, arithmetic methods return a new Decimal.
+// The zero value is 0.
+type Decimal struct {
+	rat   *big.Rat
+	scale int // fractional digits
+}
+
+// ParseDecimal parses a decimal string as sent by the binance API,
+// for example "-123.45600".
+// Exponents and fractions are not accepted.
+func ParseDecimal(s string) (Decimal, error) {
+	scale, ok := decimalScale(s)
+	if !ok {
+		return Decimal{}, fmt.Errorf("binance: invalid decimal %q", s)
+	}
+
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, fmt.Errorf("binance: invalid decimal %q", s)
+	}
+
+	return Decimal{rat: rat, scale: scale}, nil
+}
+
+// decimalScale validates s and returns the amount of fractional digits.
+func decimalScale(s string) (scale int, ok bool) {
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+
+	var digits int
+	point := -1
+
+	for i, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && point < 0:
+			point = i
+		default:
+			return 0, false
+		}
+	}
+
+	if digits == 0 {
+		return 0, false
+	}
+	if point >= 0 {
+		scale = len(s) - point - 1
+	}
+
+	return scale, true
+}
+
+func (d Decimal) r() *big.Rat {
+	if d.rat == nil {
+		return new(big.Rat)
+	}
+	return d.rat
+}
+
+func maxScale(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// String returns the decimal with its fractional digits.
+func (d Decimal) String() string {
+	return d.r().FloatString(d.scale)
+}
+
+// Rat returns a copy of the value as big.Rat.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).Set(d.r())
+}
+
+// Float64 returns the nearest float64 value.
+func (d Decimal) Float64() float64 {
+	f, _ := d.r().Float64()
+	return f
+}
+
+// Add returns d + e.
+func (d Decimal) Add(e Decimal) Decimal {
+	return Decimal{
+		rat:   new(big.Rat).Add(d.r(), e.r()),
+		scale: maxScale(d.scale, e.scale),
+	}
+}
+
+// Sub returns d - e.
+func (d Decimal) Sub(e Decimal) Decimal {
+	return Decimal{
+		rat:   new(big.Rat).Sub(d.r(), e.r()),
+		scale: maxScale(d.scale, e.scale),
+	}
+}
+
+// Mul returns d * e. The result is exact.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{
+		rat:   new(big.Rat).Mul(d.r(), e.r()),
+		scale: d.scale + e.scale,
+	}
+}
+
+// Cmp compares d and e and returns -1, 0 or +1.
+func (d Decimal) Cmp(e Decimal) int {
+	return d.r().Cmp(e.r())
+}
+
+// Sign returns -1, 0 or +1.
+func (d Decimal) Sign() int {
+	return d.r().Sign()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler,
+// so Decimal can be used in place of a string in JSON structs.
+func (d *Decimal) UnmarshalText(text []byte) (err error) {
+	*d, err = ParseDecimal(string(text))
+	return err
+}
+
+// Price of a symbol, in units of the quote asset.
+type Price struct{ Decimal }
+
+// ParsePrice parses a price string as sent by the binance API.
+func ParsePrice(s string) (Price, error) {
+	d, err := ParseDecimal(s)
+	return Price{d}, err
+}
+
+// Add returns p + q.
+func (p Price) Add(q Price) Price { return Price{p.Decimal.Add(q.Decimal)} }
+
+// Sub returns p - q.
+func (p Price) Sub(q Price) Price { return Price{p.Decimal.Sub(q.Decimal)} }
+
+// Cmp compares p and q and returns -1, 0 or +1.
+func (p Price) Cmp(q Price) int { return p.Decimal.Cmp(q.Decimal) }
+
+// Mul returns the notional value of quantity q at price p.
+func (p Price) Mul(q Quantity) Decimal { return p.Decimal.Mul(q.Decimal) }
+
+// Quantity of an asset.
+type Quantity struct{ Decimal }
+
+// ParseQuantity parses a quantity string as sent by the binance API.
+func ParseQuantity(s string) (Quantity, error) {
+	d, err := ParseDecimal(s)
+	return Quantity{d}, err
+}
+
+// Add returns q + r.
+func (q Quantity) Add(r Quantity) Quantity { return Quantity{q.Decimal.Add(r.Decimal)} }
+
+// Sub returns q - r.
+func (q Quantity) Sub(r Quantity) Quantity { return Quantity{q.Decimal.Sub(r.Decimal)} }
+
+// Cmp compares q and r and returns -1, 0 or +1.
+func (q Quantity) Cmp(r Quantity) int { return q.Decimal.Cmp(r.Decimal) }
@@ -0,0 +1,228 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{"0", "0", false},
+		{"1.5", "1.5", false},
+		{"-1.5", "-1.5", false},
+		{"+1.5", "1.5", false},
+		{"0.00100000", "0.00100000", false},
+		{".5", "0.5", false},
+		{"5.", "5", false},
+		{"12345678901.12345678", "12345678901.12345678", false},
+		{"", "", true},
+		{".", "", true},
+		{"-", "", true},
+		{"1e5", "", true},
+		{"1/3", "", true},
+		{"1.2.3", "", true},
+		{"foo", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseDecimal(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDecimal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("ParseDecimal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustParseDecimal(t testing.TB, s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDecimal_precision(t *testing.T) {
+	const quantity = "12345678901.12345678"
+
+	f, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strconv.FormatFloat(f, 'f', 8, 64); got == quantity {
+		t.Fatalf("float64 unexpectedly preserved %s", got)
+	}
+
+	if got := mustParseDecimal(t, quantity).String(); got != quantity {
+		t.Errorf("Decimal = %s, want %s", got, quantity)
+	}
+
+	// Notional: 0.1 * 3 in float64 is 0.30000000000000004
+	price := mustParseDecimal(t, "0.1")
+	qty := mustParseDecimal(t, "3")
+
+	pf, _ := strconv.ParseFloat("0.1", 64)
+	if f := pf * 3; f == 0.3 {
+		t.Fatalf("float64 unexpectedly exact: %v", f)
+	}
+	if got := price.Mul(qty).String(); got != "0.3" {
+		t.Errorf("Decimal.Mul() = %s, want 0.3", got)
+	}
+}
+
+func TestDecimal_arithmetic(t *testing.T) {
+	a := mustParseDecimal(t, "1.10")
+	b := mustParseDecimal(t, "0.205")
+
+	tests := []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"add", a.Add(b), "1.305"},
+		{"sub", a.Sub(b), "0.895"},
+		{"mul", a.Mul(b), "0.22550"},
+		{"zero add", Decimal{}.Add(a), "1.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.String() != tt.want {
+				t.Errorf("Decimal = %s, want %s", tt.got, tt.want)
+			}
+		})
+	}
+
+	if a.Cmp(b) != 1 || b.Cmp(a) != -1 || a.Cmp(a) != 0 {
+		t.Error("Decimal.Cmp() wrong order")
+	}
+	if (Decimal{}).Sign() != 0 || a.Sign() != 1 || b.Sub(a).Sign() != -1 {
+		t.Error("Decimal.Sign() wrong sign")
+	}
+	if a.String() != "1.10" || b.String() != "0.205" {
+		t.Error("Decimal arithmetic mutated operands")
+	}
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	const data = `{"price":"0.00100000","qty":"12345678901.12345678"}`
+
+	var got struct {
+		Price    Decimal `json:"price"`
+		Quantity Decimal `json:"qty"`
+	}
+
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != data {
+		t.Errorf("json.Marshal() = %s, want %s", out, data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"price":"foo"}`), &got); err == nil {
+		t.Error("json.Unmarshal() expected error")
+	}
+}
+
+func TestPrice(t *testing.T) {
+	const (
+		bid = "29123.45000000"
+		ask = "29123.46000000"
+	)
+
+	b, err := ParsePrice(bid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := ParsePrice(ask)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := a.Sub(b).String(); got != "0.01000000" {
+		t.Errorf("Price.Sub() = %s, want 0.01000000", got)
+	}
+	if got := a.Add(b).String(); got != "58246.91000000" {
+		t.Errorf("Price.Add() = %s, want 58246.91000000", got)
+	}
+	if a.Cmp(b) != 1 {
+		t.Errorf("Price.Cmp() = %d, want 1", a.Cmp(b))
+	}
+
+	q, err := ParseQuantity("0.00150000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Mul(q).String(); got != "43.6851900000000000" {
+		t.Errorf("Price.Mul() = %s, want 43.6851900000000000", got)
+	}
+
+	// Re-serialization for requests must be exact.
+	if b.String() != bid || a.String() != ask {
+		t.Errorf("Price.String() = %s %s, want %s %s", b, a, bid, ask)
+	}
+
+	if _, err := ParsePrice("foo"); err == nil {
+		t.Error("ParsePrice() expected error")
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	q, err := ParseQuantity("1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := ParseQuantity("0.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := q.Add(r).String(); got != "1.75" {
+		t.Errorf("Quantity.Add() = %s, want 1.75", got)
+	}
+	if got := q.Sub(r).String(); got != "1.25" {
+		t.Errorf("Quantity.Sub() = %s, want 1.25", got)
+	}
+	if r.Cmp(q) != -1 {
+		t.Errorf("Quantity.Cmp() = %d, want -1", r.Cmp(q))
+	}
+
+	var got struct {
+		Quantity Quantity `json:"q"`
+	}
+	if err := json.Unmarshal([]byte(`{"q":"1000.00000000"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Quantity.String() != "1000.00000000" {
+		t.Errorf("Quantity JSON = %s, want 1000.00000000", got.Quantity)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnhealthy is returned by HealthCheck when any of the checks failed.
+var ErrUnhealthy = errors.New("binance: unhealthy")
+
+// HealthStatus is the result of HealthChecker.HealthCheck.
+type HealthStatus struct {
+	Reachable bool          // The REST API answered a ping
+	Latency   time.Duration // Ping round-trip
+	PingErr   error
+
+	ClockOK     bool          // The clock offset is within MaxClockOffset
+	ClockOffset time.Duration // Measured by SyncTime
+	ClockErr    error
+
+	StreamOK    bool      // The Stream is open and not stale, or not checked
+	LastMessage time.Time // Of the Stream
+}
+
+// Healthy reports if all checks passed.
+func (h HealthStatus) Healthy() bool {
+	return h.Reachable && h.ClockOK && h.StreamOK
+}
+
+// HealthChecker aggregates the health of the REST API client and a Stream,
+// for example for a /healthz endpoint.
+type HealthChecker struct {
+	Market *MarketData
+	Stream *Stream // Optional
+
+	// MaxStaleness is the time without messages after which the Stream is unhealthy.
+	// Binance pings every 3 minutes, so even a quiet Stream receives data.
+	// Zero uses DefaultReadTimeout.
+	MaxStaleness time.Duration
+}
+
+// HealthCheck pings the API, measures the clock offset and checks the staleness
+// of the Stream. The status is always returned, with ErrUnhealthy
+// describing the failed checks.
+func (h *HealthChecker) HealthCheck(ctx context.Context) (HealthStatus, error) {
+	var (
+		status HealthStatus
+		failed []string
+	)
+
+	status.Latency, status.PingErr = h.Market.Ping(ctx)
+	status.Reachable = status.PingErr == nil
+	if !status.Reachable {
+		failed = append(failed, fmt.Sprintf("ping: %v", status.PingErr))
+	}
+
+	status.ClockOffset, status.ClockErr = h.Market.SyncTime(ctx)
+	status.ClockOK = status.ClockErr == nil && !h.Market.ClockDrifting()
+	switch {
+	case status.ClockErr != nil:
+		failed = append(failed, fmt.Sprintf("time: %v", status.ClockErr))
+	case !status.ClockOK:
+		failed = append(failed, fmt.Sprintf("clock offset %s", status.ClockOffset))
+	}
+
+	status.StreamOK = true
+	if h.Stream != nil {
+		status.LastMessage = h.Stream.LastMessage()
+
+		maxStaleness := h.MaxStaleness
+		if maxStaleness <= 0 {
+			maxStaleness = DefaultReadTimeout
+		}
+
+		switch stale := h.Stream.clock.Now().Sub(status.LastMessage); {
+		case h.Stream.ctx.Err() != nil:
+			status.StreamOK = false
+			failed = append(failed, "stream closed")
+		case stale > maxStaleness:
+			status.StreamOK = false
+			failed = append(failed, fmt.Sprintf("stream stale for %s", stale.Round(time.Second)))
+		}
+	}
+
+	if len(failed) > 0 {
+		return status, fmt.Errorf("%w: %s", ErrUnhealthy, strings.Join(failed, "; "))
+	}
+	return status, nil
+}
@@ -0,0 +1,113 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func healthHandler(reachable bool, skew time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !reachable {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v3/ping":
+			fmt.Fprint(w, `{}`)
+		case "/api/v3/time":
+			fmt.Fprintf(w, `{"serverTime":%d}`, time.Now().Add(skew).UnixMilli())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestHealthChecker_HealthCheck(t *testing.T) {
+	hosts := newTestWsServer(t, echoMethods)
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	s, err := NewStream(ctx, WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.wg.Wait()
+	defer cancel()
+
+	tests := []struct {
+		name      string
+		reachable bool
+		skew      time.Duration
+		stale     bool
+		want      HealthStatus
+	}{
+		{"healthy", true, 0, false, HealthStatus{Reachable: true, ClockOK: true, StreamOK: true}},
+		{"unreachable", false, 0, false, HealthStatus{StreamOK: true}},
+		{"skewed clock", true, time.Hour, false, HealthStatus{Reachable: true, StreamOK: true}},
+		{"stale stream", true, 0, true, HealthStatus{Reachable: true, ClockOK: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stale {
+				atomic.StoreInt64(&s.lastRead, time.Now().Add(-time.Hour).UnixNano())
+			} else {
+				s.touch()
+			}
+
+			h := &HealthChecker{
+				Market:       newTestMarketData(t, healthHandler(tt.reachable, tt.skew)),
+				Stream:       s,
+				MaxStaleness: time.Minute,
+			}
+
+			got, err := h.HealthCheck(testCTX)
+			if healthy := tt.want.Reachable && tt.want.ClockOK && tt.want.StreamOK; healthy != (err == nil) || got.Healthy() != healthy {
+				t.Errorf("HealthChecker.HealthCheck() error = %v, Healthy() = %v", err, got.Healthy())
+			}
+			if err != nil && !errors.Is(err, ErrUnhealthy) {
+				t.Errorf("HealthChecker.HealthCheck() error = %v, want %v", err, ErrUnhealthy)
+			}
+			if got.Reachable != tt.want.Reachable || got.ClockOK != tt.want.ClockOK || got.StreamOK != tt.want.StreamOK {
+				t.Errorf("HealthChecker.HealthCheck() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("closed stream", func(t *testing.T) {
+		cancel()
+		s.wg.Wait()
+
+		h := &HealthChecker{
+			Market: newTestMarketData(t, healthHandler(true, 0)),
+			Stream: s,
+		}
+		if got, err := h.HealthCheck(testCTX); got.StreamOK || !errors.Is(err, ErrUnhealthy) {
+			t.Errorf("HealthChecker.HealthCheck() = %+v, %v", got, err)
+		}
+	})
+}
@@ -19,11 +19,16 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package binance
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/muhlemmer/yatgo/internal/driver"
+	"github.com/muhlemmer/yatgo/internal/stats"
 )
 
 type KlineInterval string
@@ -46,6 +51,107 @@ const (
 	Month    KlineInterval = "1M"
 )
 
+var klineIntervalDurations = map[KlineInterval]time.Duration{
+	Minute:   time.Minute,
+	Minute3:  3 * time.Minute,
+	Minute5:  5 * time.Minute,
+	Minute15: 15 * time.Minute,
+	Minute30: 30 * time.Minute,
+	Hour:     time.Hour,
+	Hour2:    2 * time.Hour,
+	Hour4:    4 * time.Hour,
+	Hour6:    6 * time.Hour,
+	Hour8:    8 * time.Hour,
+	Hour12:   12 * time.Hour,
+	Day:      24 * time.Hour,
+	Day3:     3 * 24 * time.Hour,
+	Week:     7 * 24 * time.Hour,
+}
+
+// Duration of the interval.
+// Month is a calendar month without a fixed duration,
+// Duration returns 0 for Month and for unknown intervals.
+func (i KlineInterval) Duration() time.Duration {
+	return klineIntervalDurations[i]
+}
+
+var (
+	// ErrCalendarInterval is returned for Month, which has no fixed length.
+	ErrCalendarInterval = errors.New("binance: calendar month interval has no fixed length")
+
+	// ErrUnknownInterval is returned for intervals which are not defined.
+	ErrUnknownInterval = errors.New("binance: unknown kline interval")
+
+	// ErrInvalidCount is returned by BootstrapKlines for a count less than 1.
+	ErrInvalidCount = errors.New("binance: kline count must be positive")
+)
+
+// Seconds returns the length of the interval in seconds.
+// Month is a calendar month, for which ErrCalendarInterval is returned.
+// Use next-month calendar arithmetic (time.AddDate) instead.
+func (i KlineInterval) Seconds() (int64, error) {
+	if i == Month {
+		return 0, ErrCalendarInterval
+	}
+
+	d, ok := klineIntervalDurations[i]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownInterval, string(i))
+	}
+	return int64(d / time.Second), nil
+}
+
+// next returns the start of the kline following the kline at start,
+// in milliseconds.
+func (i KlineInterval) next(start int64) (int64, bool) {
+	if i == Month {
+		return time.UnixMilli(start).UTC().AddDate(0, 1, 0).UnixMilli(), true
+	}
+
+	d := i.Duration()
+	if d == 0 {
+		return 0, false
+	}
+
+	return start + d.Milliseconds(), true
+}
+
+// weekOffset aligns weekly klines to Monday 00:00 UTC.
+// The Unix epoch was a Thursday.
+const weekOffset = 4 * 24 * time.Hour
+
+// NextKlineClose returns the time at which the kline of interval
+// that is open at now closes, which is when the next kline starts.
+// Klines are aligned in UTC: fixed intervals to the Unix epoch,
+// weeks to Monday and months to the first day of the month.
+// If now is exactly on a boundary, the kline starting at now is used.
+// The zero time is returned for unknown intervals.
+func NextKlineClose(interval KlineInterval, now time.Time) time.Time {
+	now = now.UTC()
+
+	if interval == Month {
+		return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	d := interval.Duration()
+	if d == 0 {
+		return time.Time{}
+	}
+
+	var offset time.Duration
+	if interval == Week {
+		offset = weekOffset
+	}
+
+	elapsed := time.Duration(now.UnixNano()) - offset
+	start := elapsed - elapsed%d
+	if elapsed < 0 && elapsed%d != 0 {
+		start -= d
+	}
+
+	return time.Unix(0, int64(start+offset+d)).UTC()
+}
+
 type Kline struct {
 	Start            int64  `json:"t"` // Kline start time
 	Finish           int64  `json:"T"` // Kline close time
@@ -66,6 +172,54 @@ type Kline struct {
 	Ignore           string `json:"B"` // Ignore
 }
 
+// ParsedKline holds the numeric values of a Kline.
+type ParsedKline struct {
+	Start            int64
+	Finish           int64
+	Open             Price
+	Close            Price
+	High             Price
+	Low              Price
+	BaseVolume       Quantity
+	QuoteVolume      Quantity
+	TakerBaseVolume  Quantity
+	TakerQuoteVolume Quantity
+	Trades           int
+	Closed           bool
+}
+
+// Parse the string values of the Kline.
+func (k Kline) Parse() (p ParsedKline, err error) {
+	p = ParsedKline{
+		Start:  k.Start,
+		Finish: k.Finish,
+		Trades: k.Trades,
+		Closed: k.Closed,
+	}
+
+	fields := []struct {
+		dst *Decimal
+		src string
+	}{
+		{&p.Open.Decimal, k.Open},
+		{&p.Close.Decimal, k.Close},
+		{&p.High.Decimal, k.High},
+		{&p.Low.Decimal, k.Low},
+		{&p.BaseVolume.Decimal, k.BaseVolume},
+		{&p.QuoteVolume.Decimal, k.QuoteVolume},
+		{&p.TakerBaseVolume.Decimal, k.TakerBaseVolume},
+		{&p.TakerQuoteVolume.Decimal, k.TakerQuoteVolume},
+	}
+
+	for _, f := range fields {
+		if *f.dst, err = ParseDecimal(f.src); err != nil {
+			return ParsedKline{}, fmt.Errorf("kline parse: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
 type KlineEvent struct {
 	Event  string `json:"e"` // Event type ("kline")
 	Time   int64  `json:"E"` // Event time
@@ -80,7 +234,7 @@ type klineHandler struct {
 func (k *klineHandler) Event(data []byte) {
 	var event KlineEvent
 	if err := json.Unmarshal(data, &event); err != nil {
-		panic(fmt.Errorf("KlineHandler: %w", err))
+		panic(decodeError{fmt.Errorf("KlineHandler: %w", err)})
 	}
 
 	k.h.Event(event)
@@ -93,12 +247,78 @@ type KlineHandler interface {
 	Done()
 }
 
+// KlineGap describes klines missing from a stream.
+type KlineGap struct {
+	Symbol   string
+	Interval KlineInterval
+	From     int64 // Start of the first missing kline
+	To       int64 // Start of the kline received after the gap
+}
+
+type gapHandler struct {
+	h     KlineHandler
+	onGap func(KlineGap)
+
+	mtx    sync.Mutex
+	closed int64 // Start of the last closed kline
+	seen   int64 // Start of the newest kline
+}
+
+// DetectGaps wraps handler and calls onGap when a new kline
+// does not directly follow the last closed kline.
+// This happens when klines were dropped, for example during a reconnect.
+// The missing klines can then be fetched with MarketData.Klines.
+// All events are forwarded to handler.
+func DetectGaps(handler KlineHandler, onGap func(KlineGap)) KlineHandler {
+	return &gapHandler{
+		h:     handler,
+		onGap: onGap,
+	}
+}
+
+func (g *gapHandler) Event(event KlineEvent) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	k := event.Kline
+	interval := KlineInterval(k.Interval)
+
+	if k.Start > g.seen {
+		if next, ok := interval.next(g.closed); ok && g.closed != 0 && k.Start != next {
+			g.onGap(KlineGap{
+				Symbol:   k.Symbol,
+				Interval: interval,
+				From:     next,
+				To:       k.Start,
+			})
+		}
+		g.seen = k.Start
+	}
+
+	if k.Closed && k.Start > g.closed {
+		g.closed = k.Start
+	}
+
+	g.h.Event(event)
+}
+
+func (g *gapHandler) Done() { g.h.Done() }
+
 func klineStreamName(symbol string, interval KlineInterval) string {
 	return fmt.Sprintf("%s@kline_%s", symbol, interval)
 }
 
 func (s *Stream) SubscribeKlines(symbol string, interval KlineInterval, handler KlineHandler) error {
-	return s.Subscribe(
+	return s.SubscribeRaw(
+		[]string{klineStreamName(symbol, interval)},
+		jsonEventHandler{&klineHandler{handler}},
+	)
+}
+
+// WithKlines subscribes handler to the kline stream before NewStream returns.
+// See WithSubscription.
+func WithKlines(symbol string, interval KlineInterval, handler KlineHandler) StreamOption {
+	return WithSubscription(
 		klineStreamName(symbol, interval),
 		&klineHandler{handler},
 	)
@@ -110,30 +330,274 @@ func (s *Stream) UnsubscribeKlines(symbol string, interval KlineInterval) error
 
 type closingPriceHandler struct {
 	h driver.ClosingPriceHandler
+
+	// last is updated before each event is passed to h,
+	// and cleared on Done. Nil disables caching.
+	last   *driver.SyncMap[string, driver.ClosingPrice]
+	stream string
 }
 
 func (h *closingPriceHandler) Event(event KlineEvent) {
-	price, err := strconv.ParseFloat(event.Kline.Close, 64)
+	price, err := ParsePrice(event.Kline.Close)
 	if err != nil {
-		panic(fmt.Errorf("closing price event: %w", err))
+		panic(decodeError{fmt.Errorf("closing price event: %w", err)})
 	}
 
-	h.h.Event(driver.ClosingPrice{
-		Price:  price,
+	cp := driver.ClosingPrice{
+		Price:  price.Float64(),
 		Closed: event.Kline.Closed,
-	})
+	}
+	if h.last != nil {
+		h.last.Store(h.stream, cp)
+	}
+
+	h.h.Event(cp)
 }
 
 func (h *closingPriceHandler) Done() {
+	if h.last != nil {
+		h.last.LoadAndDelete(h.stream)
+	}
 	h.h.Done()
 }
 
 func (s *Stream) SubscribeClosingPrices(symbol string, interval string, handler driver.ClosingPriceHandler) error {
 	return s.SubscribeKlines(symbol, KlineInterval(interval),
-		&closingPriceHandler{h: handler},
+		&closingPriceHandler{
+			h:      handler,
+			last:   &s.closingPrices,
+			stream: klineStreamName(symbol, KlineInterval(interval)),
+		},
 	)
 }
 
+// LastClosingPrice returns the most recent closing price received
+// through SubscribeClosingPrices for symbol and interval.
+// False is returned when no event was received yet,
+// or the closing prices are no longer subscribed.
+// As events are dispatched concurrently, a rapid succession of events
+// may leave an older price in place until the next event.
+func (s *Stream) LastClosingPrice(symbol string, interval string) (driver.ClosingPrice, bool) {
+	return s.closingPrices.Load(klineStreamName(symbol, KlineInterval(interval)))
+}
+
 func (s *Stream) UnsubscribeClosingPrices(symbol string, interval string) error {
 	return s.UnsubscribeKlines(symbol, KlineInterval(interval))
 }
+
+// KlinesReq is the request for historical klines.
+type KlinesReq struct {
+	Symbol    string        `schema:"symbol,required,omitempty"`
+	Interval  KlineInterval `schema:"interval,required,omitempty"`
+	StartTime int64         `schema:"startTime,omitempty"`
+	EndTime   int64         `schema:"endTime,omitempty"`
+	Limit     int           `schema:"limit,omitempty"` // Default 500, max 1000
+}
+
+// MaxKlinesLimit is the maximum Limit accepted by the klines endpoint.
+const MaxKlinesLimit = 1000
+
+// klineRow decodes a kline from the array representation of the REST API.
+type klineRow Kline
+
+func (r *klineRow) UnmarshalJSON(data []byte) error {
+	fields := []interface{}{
+		&r.Start, &r.Open, &r.High, &r.Low, &r.Close, &r.BaseVolume, &r.Finish,
+		&r.QuoteVolume, &r.Trades, &r.TakerBaseVolume, &r.TakerQuoteVolume, &r.Ignore,
+	}
+	n := len(fields)
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("kline row: %w", err)
+	}
+	if len(fields) != n {
+		return fmt.Errorf("kline row: %d fields, want %d", len(fields), n)
+	}
+
+	return nil
+}
+
+// Klines fetches historical klines, oldest first.
+// Klines with a close time in the future are marked as not closed.
+func (m *MarketData) Klines(ctx context.Context, req KlinesReq) ([]Kline, error) {
+	var rows []klineRow
+	if err := m.GetJSON(ctx, "/api/v3/klines", req, &rows); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	klines := make([]Kline, len(rows))
+
+	for i, r := range rows {
+		k := Kline(r)
+		k.Symbol = req.Symbol
+		k.Interval = string(req.Interval)
+		k.Closed = k.Finish < now
+
+		klines[i] = k
+	}
+
+	return klines, nil
+}
+
+// KlineStreamer is implemented by Stream.
+type KlineStreamer interface {
+	SubscribeKlines(symbol string, interval KlineInterval, handler KlineHandler) error
+	UnsubscribeKlines(symbol string, interval KlineInterval) error
+}
+
+// bootstrapHandler buffers live events while history is replayed,
+// and drops live events for klines that were already replayed.
+type bootstrapHandler struct {
+	h KlineHandler
+
+	mtx       sync.Mutex
+	replaying bool
+	buffer    []KlineEvent
+	last      int64 // Start of the last replayed kline
+}
+
+func (b *bootstrapHandler) forward(event KlineEvent) {
+	if event.Kline.Start > b.last {
+		b.h.Event(event)
+	}
+}
+
+func (b *bootstrapHandler) Event(event KlineEvent) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.replaying {
+		b.buffer = append(b.buffer, event)
+		return
+	}
+
+	b.forward(event)
+}
+
+func (b *bootstrapHandler) Done() { b.h.Done() }
+
+// replay the closed klines to the handler and flush the buffered live events.
+func (b *bootstrapHandler) replay(klines []Kline) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, k := range klines {
+		b.h.Event(KlineEvent{
+			Event:  "kline",
+			Time:   k.Finish,
+			Symbol: k.Symbol,
+			Kline:  k,
+		})
+		b.last = k.Start
+	}
+
+	for _, event := range b.buffer {
+		b.forward(event)
+	}
+
+	b.buffer = nil
+	b.replaying = false
+}
+
+// BootstrapKlines replays the last count closed klines from history to handler,
+// and continues with the live kline stream afterwards.
+// The stream is subscribed before history is fetched, so no klines are missed.
+// Live events for klines that are part of the replayed history are dropped.
+// At most MaxKlinesLimit-1 klines are replayed.
+//
+// If fetching the history fails, the stream is unsubscribed again,
+// which calls handler.Done.
+// ErrInvalidCount is returned if count is not positive, without subscribing.
+func BootstrapKlines(ctx context.Context, m *MarketData, s KlineStreamer, symbol string, interval KlineInterval, count int, handler KlineHandler) error {
+	if count <= 0 {
+		return fmt.Errorf("BootstrapKlines: %w: %d", ErrInvalidCount, count)
+	}
+
+	b := &bootstrapHandler{
+		h:         handler,
+		replaying: true,
+	}
+
+	// Stream names are lower case, the REST API expects upper case symbols.
+	streamSymbol := strings.ToLower(symbol)
+
+	if err := s.SubscribeKlines(streamSymbol, interval, b); err != nil {
+		return fmt.Errorf("BootstrapKlines: %w", err)
+	}
+
+	limit := count + 1 // The last one is usually not closed.
+	if limit > MaxKlinesLimit {
+		limit = MaxKlinesLimit
+	}
+
+	klines, err := m.Klines(ctx, KlinesReq{
+		Symbol:   strings.ToUpper(symbol),
+		Interval: interval,
+		Limit:    limit,
+	})
+	if err != nil {
+		if uerr := s.UnsubscribeKlines(streamSymbol, interval); uerr != nil {
+			return fmt.Errorf("BootstrapKlines: %w; unsubscribe: %v", err, uerr)
+		}
+		return fmt.Errorf("BootstrapKlines: %w", err)
+	}
+
+	closed := klines[:0]
+	for _, k := range klines {
+		if k.Closed {
+			closed = append(closed, k)
+		}
+	}
+	if len(closed) > count {
+		closed = closed[len(closed)-count:]
+	}
+
+	b.replay(closed)
+	return nil
+}
+
+// PrimeIndicator warms up ind with the closing prices of the last n closed
+// klines of symbol, oldest first. At most MaxKlinesLimit-1 klines are fetched.
+// When fewer klines exist, all of them are moved in.
+// On error, ind is not modified.
+func PrimeIndicator(ctx context.Context, m *MarketData, symbol string, interval KlineInterval, ind stats.MovingIndicator, n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	limit := n + 1 // The last one is usually not closed.
+	if limit > MaxKlinesLimit {
+		limit = MaxKlinesLimit
+	}
+
+	klines, err := m.Klines(ctx, KlinesReq{
+		Symbol:   strings.ToUpper(symbol),
+		Interval: interval,
+		Limit:    limit,
+	})
+	if err != nil {
+		return fmt.Errorf("PrimeIndicator: %w", err)
+	}
+
+	closes := make([]float64, 0, len(klines))
+	for _, k := range klines {
+		if !k.Closed {
+			continue
+		}
+
+		p, err := k.Parse()
+		if err != nil {
+			return fmt.Errorf("PrimeIndicator: %w", err)
+		}
+		closes = append(closes, p.Close.Float64())
+	}
+	if len(closes) > n {
+		closes = closes[len(closes)-n:]
+	}
+
+	for _, c := range closes {
+		ind.Move(c)
+	}
+	return nil
+}
@@ -19,10 +19,21 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package binance
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/muhlemmer/yatgo/internal/driver"
+	"github.com/muhlemmer/yatgo/internal/stats"
+	"github.com/rs/zerolog"
 )
 
 type testKlineHandler struct {
@@ -243,3 +254,533 @@ func TestSubscribeClosingPrices(t *testing.T) {
 	for range h.got {
 	}
 }
+
+func TestStream_LastClosingPrice(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		var req wsMethodRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		conn.WriteJSON(streamMessage{ID: req.ID})
+		conn.WriteJSON(streamMessage{
+			Stream: "btcusdt@kline_1m",
+			Data:   []byte(`{"e":"kline","s":"BTCUSDT","k":{"c":"1.5","x":true}}`),
+		})
+
+		echoMethods(conn)
+	})
+
+	s, err := NewStream(logger.WithContext(ctx), WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.LastClosingPrice("btcusdt", "1m"); ok {
+		t.Error("Stream.LastClosingPrice() ok before subscribe")
+	}
+
+	h := newTestClosingPriceHandler(1)
+	if err = s.SubscribeClosingPrices("btcusdt", "1m", h); err != nil {
+		t.Fatal(err)
+	}
+	<-h.got
+
+	want := driver.ClosingPrice{Price: 1.5, Closed: true}
+	if got, ok := s.LastClosingPrice("btcusdt", "1m"); !ok || got != want {
+		t.Errorf("Stream.LastClosingPrice() = %v, %v, want %v, true", got, ok, want)
+	}
+	if _, ok := s.LastClosingPrice("ethusdt", "1m"); ok {
+		t.Error("Stream.LastClosingPrice() ok for unsubscribed symbol")
+	}
+
+	if err = s.UnsubscribeClosingPrices("btcusdt", "1m"); err != nil {
+		t.Fatal(err)
+	}
+	for range h.got {
+	}
+	if _, ok := s.LastClosingPrice("btcusdt", "1m"); ok {
+		t.Error("Stream.LastClosingPrice() ok after unsubscribe")
+	}
+
+	cancel()
+	s.wg.Wait()
+}
+
+func TestNextKlineClose(t *testing.T) {
+	date := func(month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(2022, month, day, hour, min, sec, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		interval KlineInterval
+		now      time.Time
+		want     time.Time
+	}{
+		{"minute", Minute, date(3, 15, 10, 7, 30), date(3, 15, 10, 8, 0)},
+		{"5 minutes", Minute5, date(3, 15, 10, 7, 30), date(3, 15, 10, 10, 0)},
+		{"on boundary", Minute5, date(3, 15, 10, 10, 0), date(3, 15, 10, 15, 0)},
+		{"4 hours", Hour4, date(3, 15, 10, 7, 30), date(3, 15, 12, 0, 0)},
+		{"day rollover", Hour12, date(3, 15, 23, 59, 59), date(3, 16, 0, 0, 0)},
+		{"day", Day, date(3, 15, 10, 7, 30), date(3, 16, 0, 0, 0)},
+		// 2022-03-17 is 19068 days after the epoch, which is divisible by 3.
+		{"3 days", Day3, date(3, 15, 10, 7, 30), date(3, 17, 0, 0, 0)},
+		// Tuesday
+		{"week", Week, date(3, 15, 10, 7, 30), date(3, 21, 0, 0, 0)},
+		{"week on monday", Week, date(3, 21, 0, 0, 0), date(3, 28, 0, 0, 0)},
+		{"week month rollover", Week, date(3, 30, 0, 0, 0), date(4, 4, 0, 0, 0)},
+		{"month", Month, date(3, 15, 10, 7, 30), date(4, 1, 0, 0, 0)},
+		{"month on boundary", Month, date(2, 1, 0, 0, 0), date(3, 1, 0, 0, 0)},
+		{"year rollover", Month, date(12, 31, 23, 59, 59), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"local time", Hour, date(3, 15, 10, 7, 30).In(time.FixedZone("CET", 3600)), date(3, 15, 11, 0, 0)},
+		{"before epoch", Day, time.Date(1969, 12, 31, 12, 0, 0, 0, time.UTC), time.Unix(0, 0).UTC()},
+		{"unknown", "foo", date(3, 15, 10, 7, 30), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextKlineClose(tt.interval, tt.now); !got.Equal(tt.want) {
+				t.Errorf("NextKlineClose() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_klineRow_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Kline
+		wantErr bool
+	}{
+		{
+			"success",
+			`[1499040000000,"0.01634790","0.80000000","0.01575800","0.01577100","148976.11427815",1499644799999,"2434.19055334",308,"1756.87402397","28.46694368","0"]`,
+			Kline{
+				Start:            1499040000000,
+				Finish:           1499644799999,
+				Open:             "0.01634790",
+				Close:            "0.01577100",
+				High:             "0.80000000",
+				Low:              "0.01575800",
+				BaseVolume:       "148976.11427815",
+				Trades:           308,
+				QuoteVolume:      "2434.19055334",
+				TakerBaseVolume:  "1756.87402397",
+				TakerQuoteVolume: "28.46694368",
+				Ignore:           "0",
+			},
+			false,
+		},
+		{
+			"short row",
+			`[1499040000000,"0.01634790"]`,
+			Kline{},
+			true,
+		},
+		{
+			"json error",
+			`{}`,
+			Kline{},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got klineRow
+
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("klineRow.UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(Kline(got), tt.want) {
+				t.Errorf("klineRow.UnmarshalJSON() = \n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarketData_Klines(t *testing.T) {
+	m := NewMarketData(GlobalHosts)
+
+	got, err := m.Klines(testCTX, KlinesReq{
+		Symbol:   "BTCUSDT",
+		Interval: Minute,
+		Limit:    5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 5 {
+		t.Fatalf("MarketData.Klines() returned %d klines, want 5", len(got))
+	}
+	for i, k := range got[:4] {
+		if !k.Closed {
+			t.Errorf("MarketData.Klines() kline %d not closed", i)
+		}
+	}
+}
+
+// klineHistoryHandler serves count minute klines, of which the last is open.
+func klineHistoryHandler(start int64, count int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rows []string
+
+		for i := 0; i < count; i++ {
+			open := start + int64(i)*time.Minute.Milliseconds()
+			finish := open + time.Minute.Milliseconds() - 1
+
+			if i == count-1 {
+				finish = time.Now().Add(time.Hour).UnixMilli()
+			}
+
+			rows = append(rows, fmt.Sprintf(`[%d,"1","1","1","1","1",%d,"1",1,"1","1","0"]`, open, finish))
+		}
+
+		fmt.Fprintf(w, "[%s]", strings.Join(rows, ","))
+	}
+}
+
+type testKlineStreamer struct {
+	live    []KlineEvent // sent during SubscribeKlines
+	handler KlineHandler
+}
+
+func (s *testKlineStreamer) SubscribeKlines(symbol string, interval KlineInterval, handler KlineHandler) error {
+	s.handler = handler
+
+	for _, event := range s.live {
+		handler.Event(event)
+	}
+
+	return nil
+}
+
+func (s *testKlineStreamer) UnsubscribeKlines(symbol string, interval KlineInterval) error {
+	s.handler.Done()
+	return nil
+}
+
+func TestBootstrapKlines(t *testing.T) {
+	const start = 1600000000000
+	minute := time.Minute.Milliseconds()
+
+	m := newTestMarketData(t, klineHistoryHandler(start, 5))
+
+	liveEvent := func(i int64, closed bool) KlineEvent {
+		return KlineEvent{Kline: Kline{Start: start + i*minute, Closed: closed}}
+	}
+
+	s := &testKlineStreamer{
+		live: []KlineEvent{
+			liveEvent(3, true), // closed kline, also in history
+			liveEvent(4, false),
+		},
+	}
+
+	h := newTestKlineHandler(100)
+
+	if err := BootstrapKlines(context.Background(), m, s, "BTCUSDT", Minute, 3, h); err != nil {
+		t.Fatal(err)
+	}
+
+	s.handler.Event(liveEvent(4, true))
+	s.handler.Event(liveEvent(5, false))
+	s.UnsubscribeKlines("btcusdt", Minute)
+
+	var got []int64
+	closed := make(map[int64]bool)
+
+	for event := range h.got {
+		i := (event.Kline.Start - start) / minute
+		got = append(got, i)
+
+		if event.Kline.Closed {
+			if closed[i] {
+				t.Errorf("BootstrapKlines() duplicate closed kline %d", i)
+			}
+			closed[i] = true
+		}
+	}
+
+	want := []int64{1, 2, 3, 4, 4, 5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BootstrapKlines() klines = %v, want %v", got, want)
+	}
+}
+
+func TestBootstrapKlines_limit(t *testing.T) {
+	var limit string
+
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit = r.URL.Query().Get("limit")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code":-1100,"msg":"bad request"}`)
+	}))
+
+	s := &testKlineStreamer{}
+	h := newTestKlineHandler(1)
+
+	if err := BootstrapKlines(context.Background(), m, s, "BTCUSDT", Minute, 5000, h); err == nil {
+		t.Error("BootstrapKlines() expected error")
+	}
+	if want := fmt.Sprint(MaxKlinesLimit); limit != want {
+		t.Errorf("BootstrapKlines() limit = %s, want %s", limit, want)
+	}
+	if _, ok := <-h.got; ok {
+		t.Error("BootstrapKlines() handler not done after failure")
+	}
+}
+
+func TestBootstrapKlines_invalidCount(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("BootstrapKlines() fetched history")
+	}))
+
+	for _, count := range []int{0, -1} {
+		s := &testKlineStreamer{}
+
+		err := BootstrapKlines(context.Background(), m, s, "BTCUSDT", Minute, count, newTestKlineHandler(1))
+		if !errors.Is(err, ErrInvalidCount) {
+			t.Errorf("BootstrapKlines(%d) error = %v, want %v", count, err, ErrInvalidCount)
+		}
+		if s.handler != nil {
+			t.Errorf("BootstrapKlines(%d) subscribed", count)
+		}
+	}
+}
+
+func TestPrimeIndicator(t *testing.T) {
+	const start = 1600000000000
+	minute := time.Minute.Milliseconds()
+
+	var query url.Values
+
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+
+		var rows []string
+		for i := 0; i < 5; i++ {
+			open := start + int64(i)*minute
+			finish := open + minute - 1
+			if i == 4 {
+				finish = time.Now().Add(time.Hour).UnixMilli()
+			}
+			rows = append(rows, fmt.Sprintf(`[%d,"1","1","1","%d.0","1",%d,"1",1,"1","1","0"]`, open, i+1, finish))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(rows, ","))
+	}))
+
+	tests := []struct {
+		name      string
+		n         int
+		want      float64
+		wantLimit string
+	}{
+		{"last closed", 3, (2.0 + 3.0 + 4.0) / 3, "4"},
+		{"fewer available", 10, (1.0 + 2.0 + 3.0 + 4.0) / 4, "11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ma := stats.NewMovingAverage(tt.n)
+
+			if err := PrimeIndicator(testCTX, m, "btcusdt", Minute, ma, tt.n); err != nil {
+				t.Fatal(err)
+			}
+			if got := ma.Avg(); got != tt.want {
+				t.Errorf("PrimeIndicator() Avg = %v, want %v", got, tt.want)
+			}
+			if got := query.Get("symbol"); got != "BTCUSDT" {
+				t.Errorf("PrimeIndicator() symbol = %s, want BTCUSDT", got)
+			}
+			if got := query.Get("limit"); got != tt.wantLimit {
+				t.Errorf("PrimeIndicator() limit = %s, want %s", got, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestPrimeIndicator_error(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[[1,"1","1","1","foo","1",2,"1",1,"1","1","0"]]`)
+	}))
+
+	ma := stats.NewMovingAverage(3)
+	if err := PrimeIndicator(testCTX, m, "BTCUSDT", Minute, ma, 3); err == nil {
+		t.Error("PrimeIndicator() expected error")
+	}
+	if got := ma.Avg(); got != 0 {
+		t.Errorf("PrimeIndicator() modified indicator on error, Avg = %v", got)
+	}
+}
+
+func TestKline_Parse(t *testing.T) {
+	k := Kline{
+		Start:            1,
+		Finish:           2,
+		Open:             "0.0010",
+		Close:            "0.0020",
+		High:             "0.0025",
+		Low:              "0.0015",
+		BaseVolume:       "1000",
+		Trades:           100,
+		Closed:           true,
+		QuoteVolume:      "1.0000",
+		TakerBaseVolume:  "500",
+		TakerQuoteVolume: "0.500",
+	}
+
+	got, err := k.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Start != 1 || got.Finish != 2 || got.Trades != 100 || !got.Closed {
+		t.Errorf("Kline.Parse() = %v", got)
+	}
+	if got.Close.String() != k.Close || got.TakerQuoteVolume.String() != k.TakerQuoteVolume {
+		t.Errorf("Kline.Parse() = %v", got)
+	}
+
+	k.High = "foo"
+	if _, err = k.Parse(); err == nil {
+		t.Error("Kline.Parse() expected error")
+	}
+}
+
+func TestKlineInterval_Duration(t *testing.T) {
+	tests := []struct {
+		interval KlineInterval
+		want     time.Duration
+	}{
+		{Minute, time.Minute},
+		{Minute15, 15 * time.Minute},
+		{Hour4, 4 * time.Hour},
+		{Day3, 72 * time.Hour},
+		{Week, 168 * time.Hour},
+		{Month, 0},
+		{"foo", 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.interval), func(t *testing.T) {
+			if got := tt.interval.Duration(); got != tt.want {
+				t.Errorf("KlineInterval.Duration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKlineInterval_Seconds(t *testing.T) {
+	tests := []struct {
+		interval KlineInterval
+		want     int64
+		wantErr  error
+	}{
+		{Minute, 60, nil},
+		{Minute3, 180, nil},
+		{Minute5, 300, nil},
+		{Minute15, 900, nil},
+		{Minute30, 1800, nil},
+		{Hour, 3600, nil},
+		{Hour2, 7200, nil},
+		{Hour4, 14400, nil},
+		{Hour6, 21600, nil},
+		{Hour8, 28800, nil},
+		{Hour12, 43200, nil},
+		{Day, 86400, nil},
+		{Day3, 259200, nil},
+		{Week, 604800, nil},
+		{Month, 0, ErrCalendarInterval},
+		{"foo", 0, ErrUnknownInterval},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.interval), func(t *testing.T) {
+			got, err := tt.interval.Seconds()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("KlineInterval.Seconds() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("KlineInterval.Seconds() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKlineInterval_next(t *testing.T) {
+	jan := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	feb := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+	if got, ok := Month.next(jan); !ok || got != feb {
+		t.Errorf("KlineInterval.next() = %v, want %v", got, feb)
+	}
+	if got, ok := Hour.next(jan); !ok || got != jan+time.Hour.Milliseconds() {
+		t.Errorf("KlineInterval.next() = %v, want %v", got, jan+time.Hour.Milliseconds())
+	}
+	if _, ok := KlineInterval("foo").next(jan); ok {
+		t.Error("KlineInterval.next() unknown interval ok")
+	}
+}
+
+func TestDetectGaps(t *testing.T) {
+	const start = 1600000000000
+	minute := time.Minute.Milliseconds()
+
+	event := func(i int64, closed bool) KlineEvent {
+		return KlineEvent{Kline: Kline{
+			Start:    start + i*minute,
+			Symbol:   "BTCUSDT",
+			Interval: string(Minute),
+			Closed:   closed,
+		}}
+	}
+
+	var gaps []KlineGap
+	inner := newTestKlineHandler(100)
+	h := DetectGaps(inner, func(gap KlineGap) {
+		gaps = append(gaps, gap)
+	})
+
+	for _, e := range []KlineEvent{
+		event(0, false),
+		event(0, true),
+		event(1, false),
+		event(1, true),
+		event(2, false),
+		event(2, true),
+	} {
+		h.Event(e)
+	}
+
+	if len(gaps) != 0 {
+		t.Fatalf("DetectGaps() contiguous klines flagged: %v", gaps)
+	}
+
+	h.Event(event(4, false)) // skipped 3
+	h.Event(event(4, false))
+	h.Event(event(4, true))
+	h.Event(event(5, false))
+	h.Done()
+
+	want := []KlineGap{{
+		Symbol:   "BTCUSDT",
+		Interval: Minute,
+		From:     start + 3*minute,
+		To:       start + 4*minute,
+	}}
+	if !reflect.DeepEqual(gaps, want) {
+		t.Errorf("DetectGaps() gaps = %v, want %v", gaps, want)
+	}
+
+	var n int
+	for range inner.got {
+		n++
+	}
+	if n != 10 {
+		t.Errorf("DetectGaps() forwarded %d events, want 10", n)
+	}
+}
@@ -0,0 +1,375 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+// window counts usage in fixed time windows,
+// the way binance accounts its rate limits.
+type window struct {
+	limit    int
+	interval time.Duration
+	start    time.Time
+	used     int
+}
+
+// reset the usage when now is in a new window.
+func (w *window) reset(now time.Time) {
+	if start := now.Truncate(w.interval); start.After(w.start) {
+		w.start = start
+		w.used = 0
+	}
+}
+
+// DefaultWeightLimit is the REQUEST_WEIGHT limit per minute of the binance API.
+const DefaultWeightLimit = 6000
+
+// ErrWeightExceedsLimit is returned when a single request
+// weighs more than the limit allows per window.
+var ErrWeightExceedsLimit = errors.New("binance: request weight exceeds limit")
+
+// WeightLimiter proactively limits the request weight sent to the REST API,
+// so that the IP based limits are never exceeded and no back-off is needed.
+// It is safe for concurrent use.
+type WeightLimiter struct {
+	mtx sync.Mutex
+	w   window
+}
+
+// NewWeightLimiter returns a limiter allowing limit weight per interval.
+func NewWeightLimiter(limit int, interval time.Duration) *WeightLimiter {
+	return &WeightLimiter{
+		w: window{
+			limit:    limit,
+			interval: interval,
+		},
+	}
+}
+
+// Take blocks until weight fits in the current window and deducts it,
+// or returns an error when ctx is done first.
+func (l *WeightLimiter) Take(ctx context.Context, weight int) error {
+	if weight > l.w.limit {
+		return ErrWeightExceedsLimit
+	}
+
+	for {
+		l.mtx.Lock()
+
+		now := time.Now()
+		l.w.reset(now)
+
+		if l.w.used+weight <= l.w.limit {
+			l.w.used += weight
+			l.mtx.Unlock()
+			return nil
+		}
+
+		wait := l.w.start.Add(l.w.interval).Sub(now)
+		l.mtx.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Used returns the weight used in the current window.
+func (l *WeightLimiter) Used() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.w.reset(time.Now())
+	return l.w.used
+}
+
+// sync the used weight with the server's accounting,
+// which includes requests from other clients on the same IP.
+func (l *WeightLimiter) sync(header http.Header) {
+	used, err := strconv.Atoi(header.Get("X-Mbx-Used-Weight-1m"))
+	if err != nil {
+		return
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.w.reset(time.Now())
+	if used > l.w.used {
+		l.w.used = used
+	}
+}
+
+// Rate limit types, as listed in exchangeInfo.
+const (
+	RateLimitRequestWeight = "REQUEST_WEIGHT"
+	RateLimitOrders        = "ORDERS"
+	RateLimitRawRequests   = "RAW_REQUESTS"
+)
+
+// RateLimit is an entry of the rateLimits in exchangeInfo.
+type RateLimit struct {
+	RateLimitType string `json:"rateLimitType"`
+	Interval      string `json:"interval"` // SECOND, MINUTE or DAY
+	IntervalNum   int    `json:"intervalNum"`
+	Limit         int    `json:"limit"`
+}
+
+var rateLimitIntervals = map[string]time.Duration{
+	"SECOND": time.Second,
+	"MINUTE": time.Minute,
+	"HOUR":   time.Hour,
+	"DAY":    24 * time.Hour,
+}
+
+// Duration of the rate limit window.
+// Returns 0 for unknown intervals.
+func (r RateLimit) Duration() time.Duration {
+	return rateLimitIntervals[r.Interval] * time.Duration(r.IntervalNum)
+}
+
+// ErrOrderRateLimit is returned when an order would exceed
+// any of the ORDERS rate limits.
+var ErrOrderRateLimit = errors.New("binance: order rate limit exhausted")
+
+// OrderLimiter counts placed orders in the windows of the
+// ORDERS rate limits, such as 50 per 10 seconds and 160000 per day.
+// Exceeding them gets the account banned, which is worse than an IP back-off.
+// It is safe for concurrent use.
+type OrderLimiter struct {
+	mtx     sync.Mutex
+	windows []window
+}
+
+// NewOrderLimiter returns a limiter for the ORDERS type limits.
+// Other limit types and unknown intervals are ignored.
+func NewOrderLimiter(limits []RateLimit) *OrderLimiter {
+	l := new(OrderLimiter)
+
+	for _, rl := range limits {
+		if d := rl.Duration(); rl.RateLimitType == RateLimitOrders && d > 0 {
+			l.windows = append(l.windows, window{
+				limit:    rl.Limit,
+				interval: d,
+			})
+		}
+	}
+
+	return l
+}
+
+// Take counts one order in all windows.
+// It does not block, as the daily window may take hours to reset.
+// Instead, an error wrapping ErrOrderRateLimit is returned
+// when any of the windows is exhausted, and nothing is counted.
+func (l *OrderLimiter) Take() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+
+	for i := range l.windows {
+		w := &l.windows[i]
+		w.reset(now)
+
+		if w.used >= w.limit {
+			return fmt.Errorf("%w: %d per %s, until %s", ErrOrderRateLimit, w.limit, w.interval, w.start.Add(w.interval))
+		}
+	}
+
+	for i := range l.windows {
+		l.windows[i].used++
+	}
+
+	return nil
+}
+
+// orderPaths are the endpoints which place orders,
+// counting towards the ORDERS rate limits.
+var orderPaths = map[string]bool{
+	"/api/v3/order":               true,
+	"/api/v3/order/oco":           true,
+	"/api/v3/order/cancelReplace": true,
+	"/api/v3/orderList/oco":       true,
+	"/api/v3/orderList/oto":       true,
+	"/api/v3/orderList/otoco":     true,
+	"/api/v3/sor/order":           true,
+}
+
+// takeOrder takes an order from the OrderLimiter, if set,
+// when a POST on path places an order.
+func (m *MarketData) takeOrder(method, path string) error {
+	if m.OrderLimiter == nil || method != http.MethodPost || !orderPaths[path] {
+		return nil
+	}
+	return m.OrderLimiter.Take()
+}
+
+// WindowStatus is the usage of a rate limit window.
+type WindowStatus struct {
+	Interval time.Duration
+	Limit    int
+	Used     int
+}
+
+// Remaining returns the usage left in the window.
+func (w WindowStatus) Remaining() int {
+	return w.Limit - w.Used
+}
+
+func (w *window) status(now time.Time) WindowStatus {
+	w.reset(now)
+	return WindowStatus{
+		Interval: w.interval,
+		Limit:    w.limit,
+		Used:     w.used,
+	}
+}
+
+// Status returns the usage of the current window.
+func (l *WeightLimiter) Status() WindowStatus {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.w.status(time.Now())
+}
+
+// Status returns the usage of each ORDERS window.
+func (l *OrderLimiter) Status() []WindowStatus {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	status := make([]WindowStatus, len(l.windows))
+	for i := range l.windows {
+		status[i] = l.windows[i].status(now)
+	}
+
+	return status
+}
+
+// RateLimitStatus is a snapshot of the rate limits of a MarketData.
+type RateLimitStatus struct {
+	Weight *WindowStatus  // Nil without a Limiter
+	Orders []WindowStatus // Nil without an OrderLimiter
+
+	BackOff          bool          // An IP back-off started by this MarketData is active
+	BackOffRemaining time.Duration // Until the back-off ends
+}
+
+// RateLimitStatus returns how close the MarketData is to its rate limits.
+// Back-offs are only reported when started by this MarketData,
+// while IPBackOff is shared by all of them.
+func (m *MarketData) RateLimitStatus() RateLimitStatus {
+	var status RateLimitStatus
+
+	if m.Limiter != nil {
+		w := m.Limiter.Status()
+		status.Weight = &w
+	}
+	if m.OrderLimiter != nil {
+		status.Orders = m.OrderLimiter.Status()
+	}
+
+	if until := atomic.LoadInt64(&m.backOffUntil); until != 0 {
+		if d := time.Unix(0, until).Sub(driver.ClockOrReal(m.Clock).Now()); d > 0 {
+			status.BackOff = true
+			status.BackOffRemaining = d
+		}
+	}
+
+	return status
+}
+
+// requestWeights of REST endpoints, by path.
+// Endpoints which are not listed weigh 1.
+var requestWeights = map[string]int{
+	"/api/v3/ping":         1,
+	"/api/v3/time":         1,
+	"/api/v3/klines":       2,
+	"/api/v3/avgPrice":     2,
+	"/api/v3/exchangeInfo": 20,
+}
+
+func requestWeight(path string, data interface{}) int {
+	switch path {
+	case "/api/v3/depth":
+		return depthWeight(data)
+	case "/api/v3/ticker/price":
+		return tickerPriceWeight(data)
+	}
+
+	if w, ok := requestWeights[path]; ok {
+		return w
+	}
+	return 1
+}
+
+func depthWeight(data interface{}) int {
+	var limit OrderBookLimit
+
+	switch req := data.(type) {
+	case OrderBookReq:
+		limit = req.Limit
+	case *OrderBookReq:
+		limit = req.Limit
+	}
+	if limit <= 0 {
+		limit = OrderBookDefault
+	}
+
+	switch {
+	case limit <= 100:
+		return 5
+	case limit <= 500:
+		return 25
+	case limit <= 1000:
+		return 50
+	default:
+		return 250
+	}
+}
+
+func tickerPriceWeight(data interface{}) int {
+	var symbol string
+
+	switch req := data.(type) {
+	case TickerPriceReq:
+		symbol = req.Symbol
+	case *TickerPriceReq:
+		symbol = req.Symbol
+	}
+	if symbol == "" {
+		return 4
+	}
+	return 2
+}
@@ -0,0 +1,291 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+func TestWeightLimiter_Take(t *testing.T) {
+	l := NewWeightLimiter(10, time.Hour)
+
+	if err := l.Take(testCTX, 6); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Take(testCTX, 4); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Used(); got != 10 {
+		t.Errorf("WeightLimiter.Used() = %d, want 10", got)
+	}
+
+	ctx, cancel := context.WithTimeout(testCTX, 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Take(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WeightLimiter.Take() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := l.Take(testCTX, 11); !errors.Is(err, ErrWeightExceedsLimit) {
+		t.Errorf("WeightLimiter.Take() error = %v, want %v", err, ErrWeightExceedsLimit)
+	}
+}
+
+func TestWeightLimiter_Take_window(t *testing.T) {
+	l := NewWeightLimiter(1, 50*time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Take(testCTX, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("WeightLimiter.Take() did not block, took %s", d)
+	}
+}
+
+func Test_requestWeight(t *testing.T) {
+	tests := []struct {
+		path string
+		data interface{}
+		want int
+	}{
+		{"/api/v3/ping", nil, 1},
+		{"/api/v3/time", nil, 1},
+		{"/api/v3/klines", KlinesReq{}, 2},
+		{"/api/v3/exchangeInfo", nil, 20},
+		{"/api/v3/unknown", nil, 1},
+		{"/api/v3/depth", OrderBookReq{}, 5},
+		{"/api/v3/depth", OrderBookReq{Limit: OrderBookLimit_500}, 25},
+		{"/api/v3/depth", &OrderBookReq{Limit: OrderBookLimit_1000}, 50},
+		{"/api/v3/depth", OrderBookReq{Limit: OrderBookLimit_5000}, 250},
+		{"/api/v3/ticker/price", TickerPriceReq{Symbol: "BTCUSDT"}, 2},
+		{"/api/v3/ticker/price", TickerPriceReq{}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.path, tt.data), func(t *testing.T) {
+			if got := requestWeight(tt.path, tt.data); got != tt.want {
+				t.Errorf("requestWeight() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarketData_GetJSON_weight(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	m.Limiter = NewWeightLimiter(DefaultWeightLimit, time.Hour)
+
+	calls := []struct {
+		path string
+		data interface{}
+		want int
+	}{
+		{"/api/v3/ping", nil, 1},
+		{"/api/v3/depth", OrderBookReq{Symbol: "BTCUSDT", Limit: OrderBookLimit_5000}, 251},
+		{"/api/v3/exchangeInfo", nil, 271},
+	}
+	for _, c := range calls {
+		if err := m.GetJSON(testCTX, c.path, c.data, &struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Limiter.Used(); got != c.want {
+			t.Errorf("%s: WeightLimiter.Used() = %d, want %d", c.path, got, c.want)
+		}
+	}
+}
+
+func TestMarketData_GetJSON_weightSync(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", "100")
+		w.Write([]byte(`{}`))
+	}))
+	m.Limiter = NewWeightLimiter(DefaultWeightLimit, time.Hour)
+
+	if err := m.GetJSON(testCTX, "/api/v3/ping", nil, &PingResp{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Limiter.Used(); got != 100 {
+		t.Errorf("WeightLimiter.Used() = %d, want 100", got)
+	}
+}
+
+func TestRateLimit_Duration(t *testing.T) {
+	tests := []struct {
+		limit RateLimit
+		want  time.Duration
+	}{
+		{RateLimit{Interval: "SECOND", IntervalNum: 10}, 10 * time.Second},
+		{RateLimit{Interval: "MINUTE", IntervalNum: 1}, time.Minute},
+		{RateLimit{Interval: "DAY", IntervalNum: 1}, 24 * time.Hour},
+		{RateLimit{Interval: "WEEK", IntervalNum: 1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.limit.Interval, func(t *testing.T) {
+			if got := tt.limit.Duration(); got != tt.want {
+				t.Errorf("RateLimit.Duration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderLimiter_Take(t *testing.T) {
+	l := NewOrderLimiter([]RateLimit{
+		{RateLimitRequestWeight, "MINUTE", 1, 1},
+		{RateLimitOrders, "SECOND", 10, 3},
+		{RateLimitOrders, "DAY", 1, 5},
+	})
+	if len(l.windows) != 2 {
+		t.Fatalf("NewOrderLimiter() windows = %d, want 2", len(l.windows))
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := l.Take(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Take(); !errors.Is(err, ErrOrderRateLimit) {
+		t.Errorf("OrderLimiter.Take() error = %v, want %v", err, ErrOrderRateLimit)
+	}
+
+	// start a new 10 second window
+	l.windows[0].start = l.windows[0].start.Add(-10 * time.Second)
+
+	for i := 0; i < 2; i++ {
+		if err := l.Take(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Take(); !errors.Is(err, ErrOrderRateLimit) {
+		t.Errorf("OrderLimiter.Take() error = %v, want %v", err, ErrOrderRateLimit)
+	}
+	if used := l.windows[0].used; used != 2 {
+		t.Errorf("OrderLimiter.Take() counted rejected order, used = %d", used)
+	}
+}
+
+func TestMarketData_LoadOrderLimits(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"timezone":"UTC","serverTime":1565246363776,"rateLimits":[
+			{"rateLimitType":"REQUEST_WEIGHT","interval":"MINUTE","intervalNum":1,"limit":6000},
+			{"rateLimitType":"ORDERS","interval":"SECOND","intervalNum":10,"limit":1}
+		]}`))
+	}))
+
+	if err := m.LoadOrderLimits(testCTX); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.OrderLimiter.Take(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.OrderLimiter.Take(); !errors.Is(err, ErrOrderRateLimit) {
+		t.Errorf("OrderLimiter.Take() error = %v, want %v", err, ErrOrderRateLimit)
+	}
+}
+
+func TestMarketData_orderLimit(t *testing.T) {
+	m, reqs := newTestSignedMarketData(t)
+	m.OrderLimiter = NewOrderLimiter([]RateLimit{
+		{RateLimitOrders, "SECOND", 10, 2},
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := m.PostSignedWeight(testCTX, "/api/v3/order", 1, testOrder, &struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The budget is used up, the order is refused before sending.
+	err := m.PostSignedWeight(testCTX, "/api/v3/order", 1, testOrder, &struct{}{})
+	if !errors.Is(err, ErrOrderRateLimit) {
+		t.Errorf("MarketData.PostSignedWeight() error = %v, want %v", err, ErrOrderRateLimit)
+	}
+	if len(*reqs) != 2 {
+		t.Errorf("MarketData.PostSignedWeight() sent %d requests, want 2", len(*reqs))
+	}
+
+	// Test orders and queries do not place orders.
+	if err = m.PostSignedWeight(testCTX, "/api/v3/order/test", 1, testOrder, &struct{}{}); err != nil {
+		t.Errorf("MarketData.PostSignedWeight(order/test) error = %v", err)
+	}
+	if err = m.GetSignedWeight(testCTX, "/api/v3/order", 2, testOrder, &struct{}{}); err != nil {
+		t.Errorf("MarketData.GetSignedWeight(order) error = %v", err)
+	}
+}
+
+func TestMarketData_RateLimitStatus(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	if got := m.RateLimitStatus(); !reflect.DeepEqual(got, RateLimitStatus{}) {
+		t.Errorf("MarketData.RateLimitStatus() = %+v, want zero", got)
+	}
+
+	clock := driver.NewFakeClock(time.Unix(1000, 0))
+	m.Clock = clock
+	m.Limiter = NewWeightLimiter(1000, time.Hour)
+	m.OrderLimiter = NewOrderLimiter([]RateLimit{
+		{RateLimitOrders, "SECOND", 10, 50},
+		{RateLimitOrders, "DAY", 1, 160000},
+	})
+
+	if err := m.OrderLimiter.Take(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.GetJSON(testCTX, "/api/v3/exchangeInfo", nil, &ExchangeInfoResp{}); err == nil {
+		t.Fatal("MarketData.GetJSON() expected back-off error")
+	}
+	clock.Advance(20 * time.Second)
+
+	want := RateLimitStatus{
+		Weight: &WindowStatus{Interval: time.Hour, Limit: 1000, Used: 20},
+		Orders: []WindowStatus{
+			{Interval: 10 * time.Second, Limit: 50, Used: 1},
+			{Interval: 24 * time.Hour, Limit: 160000, Used: 1},
+		},
+		BackOff:          true,
+		BackOffRemaining: 40 * time.Second,
+	}
+	got := m.RateLimitStatus()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MarketData.RateLimitStatus() = %+v, want %+v", got, want)
+	}
+	if r := got.Weight.Remaining(); r != 980 {
+		t.Errorf("WindowStatus.Remaining() = %d, want 980", r)
+	}
+
+	clock.Advance(40 * time.Second)
+	IPBackOff.Wait()
+
+	if got := m.RateLimitStatus(); got.BackOff || got.BackOffRemaining != 0 {
+		t.Errorf("MarketData.RateLimitStatus() back-off = %v %s after it ended", got.BackOff, got.BackOffRemaining)
+	}
+}
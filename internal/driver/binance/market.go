@@ -19,16 +19,23 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package binance
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/schema"
 	"github.com/muhlemmer/yatgo/internal/driver"
+	"github.com/rs/zerolog"
 )
 
 var (
@@ -41,13 +48,68 @@ var (
 type MarketData struct {
 	*driver.Client
 	se *schema.Encoder
+
+	// Limiter is optional and limits the request weight
+	// before requests are sent.
+	Limiter *WeightLimiter
+
+	// OrderLimiter is optional and limits the amount of orders placed.
+	// Order requests fail with ErrOrderRateLimit when it is exhausted.
+	// It can be seeded from exchangeInfo using LoadOrderLimits.
+	OrderLimiter *OrderLimiter
+
+	// Clock times the back-off and SyncTime.
+	// Nil uses the real clock.
+	Clock driver.Clock
+
+	// MaxBackOff caps the duration of the Retry-After header.
+	// Zero uses DefaultMaxBackOff.
+	MaxBackOff time.Duration
+
+	// OnBackOff and OnBackOffEnd are optional and called when a back-off
+	// starts and when its duration has passed.
+	// OnBackOffEnd is called from a timer goroutine.
+	OnBackOff    func(BackOffError)
+	OnBackOffEnd func(BackOffError)
+
+	// Retry is optional and retries requests
+	// which fail with a transient binance error code.
+	Retry *RetryPolicy
+
+	// BatchConcurrency limits the requests in flight of GetJSONBatch.
+	// Zero uses DefaultBatchConcurrency.
+	BatchConcurrency int
+
+	// APIKey and SecretKey are required for signed requests,
+	// see GetSignedWeight.
+	APIKey    string
+	SecretKey string
+
+	// RecvWindow is optional and sent with signed requests.
+	// Zero uses the server default of 5 seconds.
+	RecvWindow time.Duration
+
+	// MaxClockOffset is the threshold of ClockDrifting.
+	MaxClockOffset time.Duration
+	clockOffset    int64 // atomic, set by SyncTime
+
+	backOffUntil int64 // atomic, unix nano end of the last back-off by Clock
 }
 
-var apiHosts = []string{
-	"api.binance.com",
-	"api1.binance.com",
-	"api2.binance.com",
-	"api3.binance.com",
+// NewMarketData returns a MarketData client for the API hosts.
+// The zero Hosts value selects GlobalHosts.
+func NewMarketData(hosts Hosts) *MarketData {
+	if len(hosts.API) == 0 {
+		hosts = GlobalHosts
+	}
+
+	return &MarketData{
+		Client: &driver.Client{
+			Hosts:     append([]string(nil), hosts.API...),
+			ProbePath: "/api/v3/ping",
+		},
+		se: schema.NewEncoder(),
+	}
 }
 
 func (m *MarketData) encodeFormData(data interface{}) (url.Values, error) {
@@ -59,70 +121,241 @@ func (m *MarketData) encodeFormData(data interface{}) (url.Values, error) {
 	return values, m.se.Encode(data, values)
 }
 
+// APIError is implemented by the errors of both the REST API
+// and the websocket stream, which carry a binance error code.
+// Use errors.As to extract it regardless of transport.
+type APIError interface {
+	error
+	Code() int       // binance error code, 0 if not provided.
+	Message() string // binance error message.
+}
+
+// errorBody is the error payload of the REST API.
+type errorBody struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
 // BackOffError is returned after a 429 or 418 status code is received from the API.
 type BackOffError struct {
 	StatusCode int
 	Duration   time.Duration
+	ErrorCode  int    // from the response body, if any
+	ErrorMsg   string // from the response body, if any
 }
 
 func (e BackOffError) Error() string {
 	return fmt.Sprintf("binance: status %d, back off for %s", e.StatusCode, e.Duration)
 }
 
+func (e BackOffError) Code() int { return e.ErrorCode }
+
+// Message returns the binance error message,
+// or the Error string if the response body had none.
+func (e BackOffError) Message() string {
+	if e.ErrorMsg == "" {
+		return e.Error()
+	}
+	return e.ErrorMsg
+}
+
 // RequestError is returned on any status code that's not 200, 418 or 429.
 type RequestError struct {
 	StatusCode int
 	Status     string
+	ErrorCode  int    // from the response body, if any
+	ErrorMsg   string // from the response body, if any
 }
 
 func (e RequestError) Error() string {
+	if e.ErrorMsg != "" {
+		return fmt.Sprintf("binance: status %s, code %d, %s", e.Status, e.ErrorCode, e.ErrorMsg)
+	}
 	return fmt.Sprintf("binance: status %s", e.Status)
 }
 
+func (e RequestError) Code() int { return e.ErrorCode }
+
+// Message returns the binance error message,
+// or the HTTP status if the response body had none.
+func (e RequestError) Message() string {
+	if e.ErrorMsg == "" {
+		return e.Status
+	}
+	return e.ErrorMsg
+}
+
+// DefaultMaxBackOff is the back-off cap when MarketData.MaxBackOff is zero.
+const DefaultMaxBackOff = 5 * time.Minute
+
+func (m *MarketData) maxBackOff() time.Duration {
+	if m.MaxBackOff <= 0 {
+		return DefaultMaxBackOff
+	}
+	return m.MaxBackOff
+}
+
 // GetJSON performs a GET request on paths, with data encoded to URL values.
 // The response body is expected to be JSON and will be unmarshalled into target.
 // In case the call succeeds and the satus code is not 200, a BackOffError or RequestError will be returned.
 //
 // In case a status code 429 or 418 is received, a timer is started based on the 'Retry-After' response header.
 // Subsequent calls will block untill this timer expires. (Uses the global IPBackOff WaitGroup)
+//
+// When a Limiter is set, the request weight of path is taken from it first.
+// When Retry is set, transient errors are retried according to the policy.
 func (m *MarketData) GetJSON(ctx context.Context, path string, data, target interface{}) error {
+	return m.GetJSONWeight(ctx, path, requestWeight(path, data), data, target)
+}
+
+// GetJSONWeight is like GetJSON, with an explicit request weight.
+// Use it for endpoints of which the driver does not know the weight.
+func (m *MarketData) GetJSONWeight(ctx context.Context, path string, weight int, data, target interface{}) error {
 	values, err := m.encodeFormData(data)
 	if err != nil {
 		return fmt.Errorf("binance: %w", err)
 	}
 
+	return m.retry(ctx, func() error {
+		return m.doJSON(ctx, http.MethodGet, path, weight, values.Encode(), nil, target)
+	})
+}
+
+// retry calls do until it succeeds or Retry gives up.
+func (m *MarketData) retry(ctx context.Context, do func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := do()
+
+		d, ok := m.Retry.delay(err, attempt)
+		if !ok {
+			return err
+		}
+		zerolog.Ctx(ctx).Warn().Err(err).Int("attempt", attempt+1).Dur("delay", d).Msg("binance retry")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("binance: %w", ctx.Err())
+		case <-driver.ClockOrReal(m.Clock).After(d):
+		}
+	}
+}
+
+// formContentType is the Content-Type of request bodies.
+const formContentType = "application/x-www-form-urlencoded"
+
+// doJSON performs a single request attempt.
+// The encoded payload is sent as query for GET,
+// or as request body for POST. A POST is only sent to the first host,
+// as a server error does not tell if an order was placed.
+func (m *MarketData) doJSON(ctx context.Context, method, path string, weight int, payload string, header http.Header, target interface{}) error {
+	if err := m.takeOrder(method, path); err != nil {
+		return err
+	}
+
 	IPBackOff.Wait()
 
-	resp, err := m.Get(ctx, path, values)
+	if m.Limiter != nil {
+		if err := m.Limiter.Take(ctx, weight); err != nil {
+			return fmt.Errorf("binance: %w", err)
+		}
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	switch method {
+	case http.MethodGet:
+		resp, err = m.Send(ctx, method, path, payload, header, nil)
+	case http.MethodPost:
+		h := http.Header{"Content-Type": {formContentType}}
+		for k, v := range header {
+			h[k] = v
+		}
+		resp, err = m.SendOnce(ctx, method, path, "", h, strings.NewReader(payload))
+	default:
+		return fmt.Errorf("binance: unsupported method %s", method)
+	}
 	if err != nil {
 		return fmt.Errorf("binance: %w", err)
 	}
+	defer resp.Body.Close()
+
+	if m.Limiter != nil {
+		m.Limiter.sync(resp.Header)
+	}
 
 	if resp.StatusCode == 200 && resp.Body != nil {
 		return json.NewDecoder(resp.Body).Decode(target)
 	}
 
+	// Error bodies are optional, decoding is best effort.
+	var body errorBody
+	if resp.Body != nil {
+		json.NewDecoder(resp.Body).Decode(&body)
+	}
+
 	if resp.StatusCode == 429 || resp.StatusCode == 418 {
 		i, err := strconv.Atoi(resp.Header.Get("Retry-After"))
 		if err != nil {
 			return fmt.Errorf("binance Retry-After header: %w", err)
 		}
 
-		dt := time.Duration(i) * time.Second
+		boe := BackOffError{
+			StatusCode: resp.StatusCode,
+			Duration:   time.Duration(i) * time.Second,
+			ErrorCode:  body.Code,
+			ErrorMsg:   body.Msg,
+		}
+		if max := m.maxBackOff(); boe.Duration > max {
+			zerolog.Ctx(ctx).Warn().Dur("retry_after", boe.Duration).Dur("max", max).Msg("binance back-off capped")
+			boe.Duration = max
+		}
+
+		clock := driver.ClockOrReal(m.Clock)
+		atomic.StoreInt64(&m.backOffUntil, clock.Now().Add(boe.Duration).UnixNano())
 
 		IPBackOff.Add(1)
-		time.AfterFunc(dt, IPBackOff.Done)
+		clock.AfterFunc(boe.Duration, func() {
+			IPBackOff.Done()
+			if m.OnBackOffEnd != nil {
+				m.OnBackOffEnd(boe)
+			}
+		})
 
-		return BackOffError{
-			StatusCode: resp.StatusCode,
-			Duration:   dt,
+		if m.OnBackOff != nil {
+			m.OnBackOff(boe)
 		}
+
+		return boe
 	}
 
 	return RequestError{
 		StatusCode: resp.StatusCode,
 		Status:     resp.Status,
+		ErrorCode:  body.Code,
+		ErrorMsg:   body.Msg,
+	}
+}
+
+// OneOrMany decodes either a single JSON object or an array of objects.
+// Endpoints like ticker/price return an object when a symbol is requested,
+// and an array when all symbols are requested.
+// Pass a *OneOrMany as GetJSON target to handle both shapes.
+type OneOrMany[T any] []T
+
+func (o *OneOrMany[T]) UnmarshalJSON(data []byte) error {
+	if data = bytes.TrimLeft(data, " \t\r\n"); len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, (*[]T)(o))
 	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*o = []T{v}
+	return nil
 }
 
 type OrderBookLimit int
@@ -150,8 +383,195 @@ type OrderBookResp struct {
 	Asks         [][]string `json:"asks"`
 }
 
+// PriceLevel is a parsed order book entry.
+type PriceLevel struct {
+	Price    Price
+	Quantity Quantity
+}
+
+func parseLevels(levels [][]string) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, len(levels))
+
+	for i, level := range levels {
+		if len(level) != 2 {
+			return nil, fmt.Errorf("binance: price level with %d fields", len(level))
+		}
+
+		var err error
+		if parsed[i].Price, err = ParsePrice(level[0]); err != nil {
+			return nil, err
+		}
+		if parsed[i].Quantity, err = ParseQuantity(level[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsed, nil
+}
+
+// ParseLevels parses the bids and asks of the order book.
+func (r OrderBookResp) ParseLevels() (bids, asks []PriceLevel, err error) {
+	if bids, err = parseLevels(r.Bids); err != nil {
+		return nil, nil, fmt.Errorf("order book bids: %w", err)
+	}
+	if asks, err = parseLevels(r.Asks); err != nil {
+		return nil, nil, fmt.Errorf("order book asks: %w", err)
+	}
+
+	return bids, asks, nil
+}
+
+// OrderBookLimits are the valid order book limits, ascending.
+var OrderBookLimits = []OrderBookLimit{
+	OrderBookLimit_5,
+	OrderBookLimit_10,
+	OrderBookLimit_20,
+	OrderBookLimit_50,
+	OrderBookLimit_100,
+	OrderBookLimit_500,
+	OrderBookLimit_1000,
+	OrderBookLimit_5000,
+}
+
+// ErrOrderBookLimit is returned when more levels are requested than the API provides.
+var ErrOrderBookLimit = errors.New("binance: order book limit exceeded")
+
+// orderBookLimitFor returns the smallest valid limit of at least minLevels.
+func orderBookLimitFor(minLevels int) (OrderBookLimit, error) {
+	for _, limit := range OrderBookLimits {
+		if int(limit) >= minLevels {
+			return limit, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %d levels", ErrOrderBookLimit, minLevels)
+}
+
+// OrderBook is a parsed order book snapshot.
+type OrderBook struct {
+	LastUpdateID int64
+	Bids         []PriceLevel
+	Asks         []PriceLevel
+}
+
+// DepthSnapshot fetches the order book of symbol with at least minLevels per side,
+// using the smallest valid limit to keep the request weight low.
+// ErrOrderBookLimit is returned when minLevels exceeds the largest limit.
+func (m *MarketData) DepthSnapshot(ctx context.Context, symbol string, minLevels int) (*OrderBook, error) {
+	limit, err := orderBookLimitFor(minLevels)
+	if err != nil {
+		return nil, fmt.Errorf("MarketData.DepthSnapshot: %w", err)
+	}
+
+	var resp OrderBookResp
+	if err = m.GetJSON(ctx, "/api/v3/depth", OrderBookReq{Symbol: symbol, Limit: limit}, &resp); err != nil {
+		return nil, fmt.Errorf("MarketData.DepthSnapshot: %w", err)
+	}
+
+	bids, asks, err := resp.ParseLevels()
+	if err != nil {
+		return nil, fmt.Errorf("MarketData.DepthSnapshot: %w", err)
+	}
+
+	return &OrderBook{
+		LastUpdateID: resp.LastUpdateId,
+		Bids:         bids,
+		Asks:         asks,
+	}, nil
+}
+
+// TickerPriceReq requests the latest price of Symbol,
+// or of all symbols when Symbol is empty.
+type TickerPriceReq struct {
+	Symbol string `schema:"symbol,omitempty"`
+}
+
+type TickerPrice struct {
+	Symbol string `json:"symbol"`
+	Price  string `json:"price"`
+}
+
 type PingResp struct{}
 
+// Ping the API and return the round-trip latency.
+// Only the HTTP request on the host that answered is timed,
+// waits for back-off, the Limiter and failed fallback hosts are excluded.
+func (m *MarketData) Ping(ctx context.Context) (time.Duration, error) {
+	var (
+		mtx        sync.Mutex
+		start, end time.Time
+	)
+
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			mtx.Lock()
+			start = time.Now()
+			mtx.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			mtx.Lock()
+			end = time.Now()
+			mtx.Unlock()
+		},
+	})
+
+	if err := m.GetJSON(ctx, "/api/v3/ping", nil, &PingResp{}); err != nil {
+		return 0, err
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	return end.Sub(start), nil
+}
+
+// HostLatency is the Ping result of a single host.
+type HostLatency struct {
+	Host    string
+	Latency time.Duration
+	Err     error
+}
+
+// PingAll pings every host in turn, without falling back to other hosts.
+// It can be used to health-check the hosts or to pick the fastest one.
+func (m *MarketData) PingAll(ctx context.Context) []HostLatency {
+	hosts := m.CurrentHosts()
+	results := make([]HostLatency, len(hosts))
+
+	for i, host := range hosts {
+		single := &MarketData{
+			Client: &driver.Client{
+				Client: m.Client.Client,
+				Hosts:  []string{host},
+			},
+			se:      m.se,
+			Limiter: m.Limiter,
+		}
+
+		results[i].Host = host
+		results[i].Latency, results[i].Err = single.Ping(ctx)
+	}
+
+	return results
+}
+
 type ServerTimeResp struct {
 	ServerTime int64 `json:"serverTime"`
 }
+
+type ExchangeInfoResp struct {
+	Timezone   string      `json:"timezone"`
+	ServerTime int64       `json:"serverTime"`
+	RateLimits []RateLimit `json:"rateLimits"`
+}
+
+// LoadOrderLimits sets the OrderLimiter with the ORDERS rate limits from exchangeInfo.
+func (m *MarketData) LoadOrderLimits(ctx context.Context) error {
+	var info ExchangeInfoResp
+
+	if err := m.GetJSON(ctx, "/api/v3/exchangeInfo", nil, &info); err != nil {
+		return err
+	}
+
+	m.OrderLimiter = NewOrderLimiter(info.RateLimits)
+	return nil
+}
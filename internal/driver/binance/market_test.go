@@ -20,14 +20,37 @@ package binance
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gorilla/schema"
 	"github.com/muhlemmer/yatgo/internal/driver"
 	"github.com/rs/zerolog"
 )
 
+// newTestMarketData returns a MarketData that sends its requests
+// to a stub server, which is closed when the test ends.
+func newTestMarketData(t *testing.T, handler http.Handler) *MarketData {
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	return &MarketData{
+		Client: &driver.Client{
+			Client: *srv.Client(),
+			Hosts:  []string{srv.Listener.Addr().String()},
+		},
+		se: schema.NewEncoder(),
+	}
+}
+
 func TestMarketData_GetJSON(t *testing.T) {
 	logger := zerolog.New(zerolog.NewTestWriter(t))
 
@@ -97,7 +120,7 @@ func TestMarketData_GetJSON(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			m := &MarketData{
 				Client: &driver.Client{
-					Hosts: apiHosts,
+					Hosts: GlobalHosts.API,
 				},
 				se: schema.NewEncoder(),
 			}
@@ -127,7 +150,7 @@ func TestMarketData_GetJSON_backOff(t *testing.T) {
 
 	m := &MarketData{
 		Client: &driver.Client{
-			Hosts: apiHosts,
+			Hosts: GlobalHosts.API,
 		},
 		se: schema.NewEncoder(),
 	}
@@ -154,3 +177,445 @@ func TestMarketData_GetJSON_backOff(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    int
+		wantMessage string
+	}{
+		{
+			"back off",
+			BackOffError{StatusCode: 429, ErrorCode: -1003, ErrorMsg: "Too many requests."},
+			-1003,
+			"Too many requests.",
+		},
+		{
+			"back off without body",
+			BackOffError{StatusCode: 418, Duration: time.Second},
+			0,
+			"binance: status 418, back off for 1s",
+		},
+		{
+			"request",
+			RequestError{StatusCode: 400, Status: "400 Bad Request", ErrorCode: -1121, ErrorMsg: "Invalid symbol."},
+			-1121,
+			"Invalid symbol.",
+		},
+		{
+			"request without body",
+			RequestError{StatusCode: 502, Status: "502 Bad Gateway"},
+			0,
+			"502 Bad Gateway",
+		},
+		{
+			"websocket",
+			&wsMethodError{ErrorCode: 2, ErrorMsg: "Invalid request"},
+			2,
+			"Invalid request",
+		},
+		{
+			"wrapped",
+			fmt.Errorf("binance stream: %w", wsMethodError{ErrorCode: 3, ErrorMsg: "foobar"}),
+			3,
+			"foobar",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiErr APIError
+			if !errors.As(tt.err, &apiErr) {
+				t.Fatalf("error %T does not implement APIError", tt.err)
+			}
+			if got := apiErr.Code(); got != tt.wantCode {
+				t.Errorf("APIError.Code() = %d, want %d", got, tt.wantCode)
+			}
+			if got := apiErr.Message(); got != tt.wantMessage {
+				t.Errorf("APIError.Message() = %q, want %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestMarketData_GetJSON_errorBody(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+	}))
+
+	err := m.GetJSON(testCTX, "/api/v3/ping", nil, &PingResp{})
+
+	want := RequestError{
+		StatusCode: http.StatusBadRequest,
+		Status:     "400 Bad Request",
+		ErrorCode:  -1121,
+		ErrorMsg:   "Invalid symbol.",
+	}
+	var got RequestError
+	if !errors.As(err, &got) || got != want {
+		t.Errorf("MarketData.GetJSON() error = %#v, want %#v", err, want)
+	}
+}
+
+func TestMarketData_OnBackOff(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	started := make(chan BackOffError, 1)
+	ended := make(chan BackOffError, 1)
+	m.OnBackOff = func(boe BackOffError) { started <- boe }
+	m.OnBackOffEnd = func(boe BackOffError) { ended <- boe }
+
+	err := m.GetJSON(testCTX, "/api/v3/ping", nil, &PingResp{})
+
+	var boe BackOffError
+	if !errors.As(err, &boe) {
+		t.Fatalf("MarketData.GetJSON() error = %v, want %T", err, boe)
+	}
+
+	want := BackOffError{StatusCode: http.StatusTooManyRequests, Duration: time.Second}
+	select {
+	case got := <-started:
+		if got != want {
+			t.Errorf("OnBackOff() = %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("OnBackOff not called")
+	}
+
+	select {
+	case got := <-ended:
+		if got != want {
+			t.Errorf("OnBackOffEnd() = %v, want %v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnBackOffEnd not called")
+	}
+
+	IPBackOff.Wait()
+}
+
+func TestMarketData_backOff_fakeClock(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	clock := driver.NewFakeClock(time.Unix(0, 0))
+	m.Clock = clock
+
+	var ended int32
+	m.OnBackOffEnd = func(BackOffError) { atomic.StoreInt32(&ended, 1) }
+
+	if err := m.GetJSON(testCTX, "/api/v3/ping", nil, &PingResp{}); err == nil {
+		t.Fatal("MarketData.GetJSON() expected back-off error")
+	}
+
+	clock.Advance(59 * time.Second)
+	if atomic.LoadInt32(&ended) != 0 {
+		t.Fatal("back-off ended before Retry-After")
+	}
+
+	clock.Advance(time.Second)
+	if atomic.LoadInt32(&ended) != 1 {
+		t.Fatal("back-off did not end after Retry-After")
+	}
+	IPBackOff.Wait()
+}
+
+func TestMarketData_MaxBackOff(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		max        time.Duration
+		want       time.Duration
+	}{
+		{"capped", "86400", 50 * time.Millisecond, 50 * time.Millisecond},
+		{"shorter", "1", time.Hour, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", tt.retryAfter)
+				w.WriteHeader(http.StatusTeapot)
+			}))
+			m.MaxBackOff = tt.max
+
+			var boe BackOffError
+			if err := m.GetJSON(testCTX, "/api/v3/ping", nil, &PingResp{}); !errors.As(err, &boe) {
+				t.Fatalf("MarketData.GetJSON() error = %v, want %T", err, boe)
+			}
+			IPBackOff.Wait()
+
+			if boe.Duration != tt.want {
+				t.Errorf("MarketData back-off = %s, want %s", boe.Duration, tt.want)
+			}
+		})
+	}
+
+	if got := new(MarketData).maxBackOff(); got != DefaultMaxBackOff {
+		t.Errorf("MarketData.maxBackOff() = %s, want %s", got, DefaultMaxBackOff)
+	}
+}
+
+func TestNewMarketData(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	tests := []struct {
+		name  string
+		hosts Hosts
+	}{
+		{"default", Hosts{}},
+		{"global", GlobalHosts},
+		{"us", USHosts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMarketData(tt.hosts)
+
+			err := m.GetJSON(logger.WithContext(testCTX), "/api/v3/ping", nil, &PingResp{})
+
+			var re RequestError
+			if errors.As(err, &re) && (re.StatusCode == http.StatusForbidden || re.StatusCode == http.StatusUnavailableForLegalReasons) {
+				t.Skipf("region blocked: %v", err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestOrderBookResp_ParseLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     OrderBookResp
+		wantBids []string
+		wantAsks []string
+		wantErr  bool
+	}{
+		{
+			"success",
+			OrderBookResp{
+				Bids: [][]string{{"4.00000000", "431.00000000"}},
+				Asks: [][]string{{"4.00000200", "12.00000000"}, {"4.00000300", "1.00000000"}},
+			},
+			[]string{"4.00000000 431.00000000"},
+			[]string{"4.00000200 12.00000000", "4.00000300 1.00000000"},
+			false,
+		},
+		{
+			"bid error",
+			OrderBookResp{
+				Bids: [][]string{{"foo", "431.00000000"}},
+			},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"ask error",
+			OrderBookResp{
+				Asks: [][]string{{"4.00000200"}},
+			},
+			nil,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bids, asks, err := tt.resp.ParseLevels()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OrderBookResp.ParseLevels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for i, level := range bids {
+				if got := level.Price.String() + " " + level.Quantity.String(); got != tt.wantBids[i] {
+					t.Errorf("OrderBookResp.ParseLevels() bid = %s, want %s", got, tt.wantBids[i])
+				}
+			}
+			for i, level := range asks {
+				if got := level.Price.String() + " " + level.Quantity.String(); got != tt.wantAsks[i] {
+					t.Errorf("OrderBookResp.ParseLevels() ask = %s, want %s", got, tt.wantAsks[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_orderBookLimitFor(t *testing.T) {
+	tests := []struct {
+		minLevels int
+		want      OrderBookLimit
+		wantErr   error
+	}{
+		{0, OrderBookLimit_5, nil},
+		{5, OrderBookLimit_5, nil},
+		{6, OrderBookLimit_10, nil},
+		{30, OrderBookLimit_50, nil},
+		{1000, OrderBookLimit_1000, nil},
+		{1001, OrderBookLimit_5000, nil},
+		{5001, 0, ErrOrderBookLimit},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.minLevels), func(t *testing.T) {
+			got, err := orderBookLimitFor(tt.minLevels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("orderBookLimitFor() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("orderBookLimitFor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarketData_DepthSnapshot_offline(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("limit"); got != "50" {
+			t.Errorf("DepthSnapshot() limit = %s, want 50", got)
+		}
+		fmt.Fprint(w, `{"lastUpdateId":42,"bids":[["1.5","2"]],"asks":[["1.6","3"]]}`)
+	}))
+
+	got, err := m.DepthSnapshot(testCTX, "BTCUSDT", 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastUpdateID != 42 || len(got.Bids) != 1 || len(got.Asks) != 1 {
+		t.Fatalf("DepthSnapshot() = %+v", got)
+	}
+	if p := got.Asks[0].Price.String(); p != "1.6" {
+		t.Errorf("DepthSnapshot() ask price = %s, want 1.6", p)
+	}
+}
+
+func TestMarketData_DepthSnapshot(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	m := NewMarketData(GlobalHosts)
+
+	got, err := m.DepthSnapshot(logger.WithContext(testCTX), "BTCUSDT", 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Bids) < 30 || len(got.Asks) < 30 || got.LastUpdateID == 0 {
+		t.Errorf("DepthSnapshot() = %d bids, %d asks, last update %d", len(got.Bids), len(got.Asks), got.LastUpdateID)
+	}
+}
+
+func TestOneOrMany(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+			fmt.Fprintf(w, `{"symbol":%q,"price":"1.00"}`, symbol)
+			return
+		}
+		w.Write([]byte(` [{"symbol":"BTCUSDT","price":"1.00"},{"symbol":"ETHUSDT","price":"2.00"}]`))
+	}))
+
+	tests := []struct {
+		name    string
+		req     TickerPriceReq
+		want    OneOrMany[TickerPrice]
+		wantErr bool
+	}{
+		{
+			"one",
+			TickerPriceReq{Symbol: "BTCUSDT"},
+			OneOrMany[TickerPrice]{
+				{Symbol: "BTCUSDT", Price: "1.00"},
+			},
+			false,
+		},
+		{
+			"many",
+			TickerPriceReq{},
+			OneOrMany[TickerPrice]{
+				{Symbol: "BTCUSDT", Price: "1.00"},
+				{Symbol: "ETHUSDT", Price: "2.00"},
+			},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got OneOrMany[TickerPrice]
+
+			err := m.GetJSON(testCTX, "/api/v3/ticker/price", tt.req, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MarketData.GetJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MarketData.GetJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var got OneOrMany[TickerPrice]
+	if err := json.Unmarshal([]byte(`"foo"`), &got); err == nil {
+		t.Error("OneOrMany.UnmarshalJSON() expected error")
+	}
+}
+
+func TestMarketData_Ping(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/ping" {
+			t.Errorf("Ping path = %s", r.URL.Path)
+		}
+		w.Write([]byte(`{}`))
+	}))
+
+	got, err := m.Ping(testCTX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got <= 0 {
+		t.Errorf("MarketData.Ping() = %s, want > 0", got)
+	}
+}
+
+func TestMarketData_Ping_fallback(t *testing.T) {
+	var calls int32
+
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	m.Hosts = append(m.Hosts, m.Hosts[0])
+
+	start := time.Now()
+
+	got, err := m.Ping(testCTX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got <= 0 || got >= time.Since(start)-150*time.Millisecond {
+		t.Errorf("MarketData.Ping() = %s, includes the failed host", got)
+	}
+}
+
+func TestMarketData_PingAll(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	m.Hosts = append(m.Hosts, "127.0.0.1:1")
+
+	got := m.PingAll(testCTX)
+	if len(got) != 2 {
+		t.Fatalf("MarketData.PingAll() = %v, want 2 results", got)
+	}
+
+	if got[0].Host != m.Hosts[0] || got[0].Err != nil || got[0].Latency <= 0 {
+		t.Errorf("MarketData.PingAll() first host = %+v", got[0])
+	}
+	if got[1].Host != "127.0.0.1:1" || got[1].Err == nil || got[1].Latency != 0 {
+		t.Errorf("MarketData.PingAll() second host = %+v", got[1])
+	}
+}
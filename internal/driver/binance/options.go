@@ -0,0 +1,253 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+// StreamOption configures a Stream created by NewStream.
+type StreamOption func(*streamConfig)
+
+type streamConfig struct {
+	hosts        Hosts
+	subs         []subscription
+	writeTimeout time.Duration
+	readTimeout  time.Duration
+	subsInURL    bool
+	openJitter   time.Duration
+	panicPolicy  PanicPolicy
+	panicHook    PanicHook
+	header       http.Header
+	dialer       *websocket.Dialer
+	queueSize    int
+	clock        driver.Clock
+	openLimiter  limiter
+	sendLimiter  limiter
+}
+
+func (cfg *streamConfig) endpoint() string {
+	if !cfg.subsInURL {
+		return cfg.hosts.streamEndpoint()
+	}
+
+	streams := make([]string, len(cfg.subs))
+	for i, sub := range cfg.subs {
+		streams[i] = sub.stream
+	}
+	return cfg.hosts.streamURL(streams)
+}
+
+// DefaultWriteTimeout is the write deadline of each message sent on a Stream.
+const DefaultWriteTimeout = 10 * time.Second
+
+// DefaultReadTimeout is the time after which a silent connection is considered dead.
+// Binance pings every 3 minutes, which keeps low-volume streams alive.
+const DefaultReadTimeout = 5 * time.Minute
+
+func newStreamConfig(opts []StreamOption) *streamConfig {
+	cfg := &streamConfig{
+		hosts:        GlobalHosts,
+		writeTimeout: DefaultWriteTimeout,
+		readTimeout:  DefaultReadTimeout,
+		queueSize:    DefaultQueueSize,
+		clock:        driver.RealClock{},
+		openLimiter:  newStreamLimiter,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithHosts selects the region hosts to connect to.
+// Defaults to GlobalHosts.
+// Hosts without WsBase select GlobalHosts, like in NewMarketData.
+func WithHosts(hosts Hosts) StreamOption {
+	return func(cfg *streamConfig) {
+		if hosts.WsBase == "" {
+			hosts = GlobalHosts
+		}
+		cfg.hosts = hosts
+	}
+}
+
+// WithWriteTimeout sets the deadline for sending a single message.
+// A stalled connection, which does not accept data within d,
+// is considered broken and closes the Stream.
+// Zero disables the deadline. Defaults to DefaultWriteTimeout.
+func WithWriteTimeout(d time.Duration) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.writeTimeout = d
+	}
+}
+
+// WithReadTimeout sets the time in which any message, ping or pong
+// must be received. A silent connection is considered dead and closes the Stream.
+// As the server pings regulary, d should be well above the ping interval.
+// Zero disables the deadline. Defaults to DefaultReadTimeout.
+func WithReadTimeout(d time.Duration) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.readTimeout = d
+	}
+}
+
+// DefaultQueueSize is the capacity of the method request queue.
+const DefaultQueueSize = 64
+
+// WithQueueSize sets the capacity of the method request queue.
+// Requests are sent at most 5 per second, to stay within the message rate limit,
+// so the queue absorbs bursts like subscribing to many streams at startup.
+// Methods block once the queue is full. Defaults to DefaultQueueSize.
+// Zero makes the queue unbuffered, so that each method blocks until it is sent.
+// A negative n selects DefaultQueueSize.
+func WithQueueSize(n int) StreamOption {
+	return func(cfg *streamConfig) {
+		if n < 0 {
+			n = DefaultQueueSize
+		}
+		cfg.queueSize = n
+	}
+}
+
+// WithDialer sets the websocket dialer used to open the connection.
+// For example, to pin the server certificate with a custom
+// TLSClientConfig. Defaults to websocket.DefaultDialer.
+func WithDialer(dialer *websocket.Dialer) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.dialer = dialer
+	}
+}
+
+// WithClock sets the clock used for the LastMessage time,
+// the open jitter wait and the SubscribeTimed latency.
+// Defaults to the real clock.
+// Connection deadlines always use the real clock.
+func WithClock(clock driver.Clock) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.clock = driver.ClockOrReal(clock)
+	}
+}
+
+// withLimiters replaces the connection and message rate limiters.
+// A nil send limiter creates a new one of 5 messages per second.
+func withLimiters(open, send limiter) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.openLimiter = open
+		cfg.sendLimiter = send
+	}
+}
+
+// WithOpenJitter delays opening the connection by a random duration up to d,
+// after the connection rate limiter allowed it.
+// This spreads out bursts of connections, for example when many
+// streams reconnect at once after a network failure.
+// Defaults to 0, no jitter.
+func WithOpenJitter(d time.Duration) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.openJitter = d
+	}
+}
+
+// WithHeader sets the request headers of the websocket handshake,
+// such as an API key or a custom User-Agent.
+// Headers of multiple calls are merged.
+func WithHeader(header http.Header) StreamOption {
+	return func(cfg *streamConfig) {
+		if cfg.header == nil {
+			cfg.header = make(http.Header)
+		}
+		for k, v := range header {
+			cfg.header[k] = append(cfg.header[k], v...)
+		}
+	}
+}
+
+// WithSubscription registers handler for stream before NewStream returns.
+// All subscriptions of a NewStream call are sent as a single SUBSCRIBE request,
+// directly after the connection is established.
+// If the request fails, NewStream closes the connection and returns an error.
+// See WithSubscriptionsInURL to subscribe on connect instead.
+func WithSubscription(stream string, handler driver.JSONHandler) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.subs = append(cfg.subs, subscription{stream, driver.ContextAdapter(handler)})
+	}
+}
+
+// WithSubscriptionsInURL makes NewStream encode the streams of
+// the subscription options in the connection URL (`/stream?streams=a/b`),
+// instead of sending a SUBSCRIBE request after connecting.
+// Binance subscribes to the streams on connect,
+// saving a round-trip and a request against the message rate limit.
+// The connection remains a combined stream, so the
+// `combined` property does not need to be set.
+func WithSubscriptionsInURL() StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.subsInURL = true
+	}
+}
+
+// WithContextSubscription is like WithSubscription, for a handler
+// which receives the Stream's context.
+func WithContextSubscription(stream string, handler driver.ContextHandler) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.subs = append(cfg.subs, subscription{stream, handler})
+	}
+}
+
+// PanicPolicy determines how handler panics in the stream's dispatcher are treated.
+type PanicPolicy int
+
+const (
+	// Recover and log panics with an error value.
+	// Panics with other values are propagated. This is the default.
+	Recover PanicPolicy = iota
+
+	// RecoverAndNotify recovers and logs all panics,
+	// and calls the PanicHook.
+	RecoverAndNotify
+
+	// Propagate all panics, crashing the program.
+	// Useful during development and testing.
+	Propagate
+)
+
+// PanicHook is called with the stream name and the recovered value,
+// under the RecoverAndNotify policy.
+type PanicHook func(stream string, value interface{})
+
+// WithPanicPolicy sets the handling of handler panics. Defaults to Recover.
+func WithPanicPolicy(policy PanicPolicy) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.panicPolicy = policy
+	}
+}
+
+// WithPanicHook sets the hook called under the RecoverAndNotify policy.
+func WithPanicHook(hook PanicHook) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.panicHook = hook
+	}
+}
@@ -0,0 +1,49 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import "testing"
+
+func Test_streamConfig_endpoint(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []StreamOption
+		want string
+	}{
+		{"default", nil, EndpointWsStream},
+		{"zero hosts", []StreamOption{WithHosts(Hosts{})}, EndpointWsStream},
+		{"us", []StreamOption{WithHosts(USHosts)}, "wss://stream.binance.us:9443/stream"},
+		{
+			"subscriptions in URL",
+			[]StreamOption{
+				WithSubscriptionsInURL(),
+				WithKlines("btcusdt", Minute, newTestKlineHandler(1)),
+				WithKlines("ethusdt", Minute, newTestKlineHandler(1)),
+			},
+			EndpointWsStream + "?streams=btcusdt@kline_1m/ethusdt@kline_1m",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newStreamConfig(tt.opts).endpoint(); got != tt.want {
+				t.Errorf("streamConfig.endpoint() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
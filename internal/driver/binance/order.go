@@ -0,0 +1,304 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"errors"
+	"fmt"
+)
+
+type OrderSide string
+
+const (
+	Buy  OrderSide = "BUY"
+	Sell OrderSide = "SELL"
+)
+
+type OrderType string
+
+const (
+	OrderLimit           OrderType = "LIMIT"
+	OrderMarket          OrderType = "MARKET"
+	OrderStopLoss        OrderType = "STOP_LOSS"
+	OrderStopLossLimit   OrderType = "STOP_LOSS_LIMIT"
+	OrderTakeProfit      OrderType = "TAKE_PROFIT"
+	OrderTakeProfitLimit OrderType = "TAKE_PROFIT_LIMIT"
+	OrderLimitMaker      OrderType = "LIMIT_MAKER"
+)
+
+type TimeInForce string
+
+const (
+	GoodTillCanceled  TimeInForce = "GTC"
+	ImmediateOrCancel TimeInForce = "IOC"
+	FillOrKill        TimeInForce = "FOK"
+)
+
+// SelfTradePrevention modes, which prevent orders of the same account
+// from matching each other.
+type SelfTradePrevention string
+
+const (
+	ExpireTaker SelfTradePrevention = "EXPIRE_TAKER"
+	ExpireMaker SelfTradePrevention = "EXPIRE_MAKER"
+	ExpireBoth  SelfTradePrevention = "EXPIRE_BOTH"
+)
+
+// NewOrderReq is the request to place an order.
+// Use OrderBuilder to create a valid request.
+type NewOrderReq struct {
+	Symbol                  string              `schema:"symbol,required,omitempty"`
+	Side                    OrderSide           `schema:"side,required,omitempty"`
+	Type                    OrderType           `schema:"type,required,omitempty"`
+	TimeInForce             TimeInForce         `schema:"timeInForce,omitempty"`
+	Quantity                string              `schema:"quantity,omitempty"`
+	QuoteOrderQty           string              `schema:"quoteOrderQty,omitempty"` // MARKET only
+	Price                   string              `schema:"price,omitempty"`
+	NewClientOrderID        string              `schema:"newClientOrderId,omitempty"`
+	StopPrice               string              `schema:"stopPrice,omitempty"`
+	IcebergQty              string              `schema:"icebergQty,omitempty"`
+	SelfTradePreventionMode SelfTradePrevention `schema:"selfTradePreventionMode,omitempty"`
+}
+
+// ErrInvalidOrder is returned by OrderBuilder.Build for invalid field combinations.
+var ErrInvalidOrder = errors.New("binance: invalid order")
+
+// OrderBuilder builds a NewOrderReq and validates its field combinations
+// before it is sent.
+//
+//	req, err := NewOrderBuilder("BTCUSDT", Buy).
+//		Limit(price).
+//		Quantity(qty).
+//		TimeInForce(GoodTillCanceled).
+//		Build()
+type OrderBuilder struct {
+	symbol string
+	side   OrderSide
+	typ    OrderType
+
+	timeInForce   TimeInForce
+	quantity      Quantity
+	quoteQuantity Quantity
+	price         Price
+	stopPrice     Price
+	icebergQty    Quantity
+	clientOrderID string
+	stp           SelfTradePrevention
+}
+
+// NewOrderBuilder starts an order of side for symbol.
+// One of the order type methods, like Limit or Market, must be called.
+func NewOrderBuilder(symbol string, side OrderSide) *OrderBuilder {
+	return &OrderBuilder{symbol: symbol, side: side}
+}
+
+// Limit makes a LIMIT order at price.
+func (b *OrderBuilder) Limit(price Price) *OrderBuilder {
+	b.typ, b.price = OrderLimit, price
+	return b
+}
+
+// Market makes a MARKET order.
+func (b *OrderBuilder) Market() *OrderBuilder {
+	b.typ = OrderMarket
+	return b
+}
+
+// LimitMaker makes a LIMIT_MAKER order at price,
+// which is rejected if it would match immediately.
+func (b *OrderBuilder) LimitMaker(price Price) *OrderBuilder {
+	b.typ, b.price = OrderLimitMaker, price
+	return b
+}
+
+// StopLoss makes a STOP_LOSS order, a market order at the StopPrice.
+func (b *OrderBuilder) StopLoss() *OrderBuilder {
+	b.typ = OrderStopLoss
+	return b
+}
+
+// StopLossLimit makes a STOP_LOSS_LIMIT order at price, placed at the StopPrice.
+func (b *OrderBuilder) StopLossLimit(price Price) *OrderBuilder {
+	b.typ, b.price = OrderStopLossLimit, price
+	return b
+}
+
+// TakeProfit makes a TAKE_PROFIT order, a market order at the StopPrice.
+func (b *OrderBuilder) TakeProfit() *OrderBuilder {
+	b.typ = OrderTakeProfit
+	return b
+}
+
+// TakeProfitLimit makes a TAKE_PROFIT_LIMIT order at price, placed at the StopPrice.
+func (b *OrderBuilder) TakeProfitLimit(price Price) *OrderBuilder {
+	b.typ, b.price = OrderTakeProfitLimit, price
+	return b
+}
+
+// Quantity sets the order quantity in the base asset.
+func (b *OrderBuilder) Quantity(q Quantity) *OrderBuilder {
+	b.quantity = q
+	return b
+}
+
+// QuoteQuantity sets the amount of the quote asset to spend or receive,
+// instead of Quantity. Only valid for MARKET orders.
+func (b *OrderBuilder) QuoteQuantity(q Quantity) *OrderBuilder {
+	b.quoteQuantity = q
+	return b
+}
+
+// TimeInForce is required for LIMIT, STOP_LOSS_LIMIT and TAKE_PROFIT_LIMIT orders.
+func (b *OrderBuilder) TimeInForce(tif TimeInForce) *OrderBuilder {
+	b.timeInForce = tif
+	return b
+}
+
+// StopPrice sets the trigger price of stop-loss and take-profit orders.
+func (b *OrderBuilder) StopPrice(p Price) *OrderBuilder {
+	b.stopPrice = p
+	return b
+}
+
+// IcebergQuantity shows only q of the order in the order book.
+// Only valid for limit orders with GoodTillCanceled.
+func (b *OrderBuilder) IcebergQuantity(q Quantity) *OrderBuilder {
+	b.icebergQty = q
+	return b
+}
+
+// ClientOrderID sets a unique ID for the order.
+// By default binance generates one.
+func (b *OrderBuilder) ClientOrderID(id string) *OrderBuilder {
+	b.clientOrderID = id
+	return b
+}
+
+// SelfTradePrevention sets the self-trade prevention mode.
+func (b *OrderBuilder) SelfTradePrevention(mode SelfTradePrevention) *OrderBuilder {
+	b.stp = mode
+	return b
+}
+
+// orderFields lists the fields of each order type, which are required or allowed.
+var orderFields = map[OrderType]struct {
+	price, stopPrice, timeInForce bool
+}{
+	OrderLimit:           {price: true, timeInForce: true},
+	OrderMarket:          {},
+	OrderStopLoss:        {stopPrice: true},
+	OrderStopLossLimit:   {price: true, stopPrice: true, timeInForce: true},
+	OrderTakeProfit:      {stopPrice: true},
+	OrderTakeProfitLimit: {price: true, stopPrice: true, timeInForce: true},
+	OrderLimitMaker:      {price: true},
+}
+
+func (b *OrderBuilder) validate() error {
+	if b.symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if b.side != Buy && b.side != Sell {
+		return fmt.Errorf("invalid side %q", b.side)
+	}
+
+	fields, ok := orderFields[b.typ]
+	if !ok {
+		return errors.New("order type is required")
+	}
+
+	checks := []struct {
+		set, want bool
+		name      string
+	}{
+		{b.price.Sign() != 0, fields.price, "price"},
+		{b.stopPrice.Sign() != 0, fields.stopPrice, "stopPrice"},
+		{b.timeInForce != "", fields.timeInForce, "timeInForce"},
+	}
+	for _, c := range checks {
+		if c.want && !c.set {
+			return fmt.Errorf("%s is required for %s orders", c.name, b.typ)
+		}
+		if c.set && !c.want {
+			return fmt.Errorf("%s is not valid for %s orders", c.name, b.typ)
+		}
+	}
+
+	for _, d := range []Decimal{b.price.Decimal, b.stopPrice.Decimal, b.quantity.Decimal, b.quoteQuantity.Decimal, b.icebergQty.Decimal} {
+		if d.Sign() < 0 {
+			return fmt.Errorf("negative value %s", d)
+		}
+	}
+
+	switch {
+	case b.quoteQuantity.Sign() != 0 && b.typ != OrderMarket:
+		return fmt.Errorf("quoteOrderQty is not valid for %s orders", b.typ)
+	case b.quoteQuantity.Sign() != 0 && b.quantity.Sign() != 0:
+		return errors.New("quantity and quoteOrderQty are mutually exclusive")
+	case b.quoteQuantity.Sign() == 0 && b.quantity.Sign() == 0:
+		return errors.New("quantity is required")
+	}
+
+	if b.icebergQty.Sign() != 0 {
+		if !fields.price {
+			return fmt.Errorf("icebergQty is not valid for %s orders", b.typ)
+		}
+		if b.timeInForce != "" && b.timeInForce != GoodTillCanceled {
+			return fmt.Errorf("icebergQty requires timeInForce %s", GoodTillCanceled)
+		}
+		if b.icebergQty.Cmp(b.quantity) >= 0 {
+			return errors.New("icebergQty must be less than quantity")
+		}
+	}
+
+	return nil
+}
+
+// Build validates the order and returns the request.
+// Invalid combinations return an error wrapping ErrInvalidOrder.
+func (b *OrderBuilder) Build() (NewOrderReq, error) {
+	if err := b.validate(); err != nil {
+		return NewOrderReq{}, fmt.Errorf("%w: %v", ErrInvalidOrder, err)
+	}
+
+	req := NewOrderReq{
+		Symbol:                  b.symbol,
+		Side:                    b.side,
+		Type:                    b.typ,
+		TimeInForce:             b.timeInForce,
+		NewClientOrderID:        b.clientOrderID,
+		SelfTradePreventionMode: b.stp,
+	}
+
+	for _, f := range []struct {
+		dst *string
+		src Decimal
+	}{
+		{&req.Quantity, b.quantity.Decimal},
+		{&req.QuoteOrderQty, b.quoteQuantity.Decimal},
+		{&req.Price, b.price.Decimal},
+		{&req.StopPrice, b.stopPrice.Decimal},
+		{&req.IcebergQty, b.icebergQty.Decimal},
+	} {
+		if f.src.Sign() != 0 {
+			*f.dst = f.src.String()
+		}
+	}
+
+	return req, nil
+}
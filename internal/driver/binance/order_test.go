@@ -0,0 +1,252 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/schema"
+)
+
+func TestOrderBuilder_Build(t *testing.T) {
+	price := Price{mustParseDecimal(t, "20000.00")}
+	stop := Price{mustParseDecimal(t, "19500.00")}
+	qty := Quantity{mustParseDecimal(t, "0.01000")}
+	iceberg := Quantity{mustParseDecimal(t, "0.002")}
+
+	tests := []struct {
+		name    string
+		build   func() *OrderBuilder
+		want    NewOrderReq
+		wantErr bool
+	}{
+		{
+			"limit",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Limit(price).Quantity(qty).TimeInForce(GoodTillCanceled).
+					IcebergQuantity(iceberg).ClientOrderID("my-order").SelfTradePrevention(ExpireTaker)
+			},
+			NewOrderReq{
+				Symbol:                  "BTCUSDT",
+				Side:                    Buy,
+				Type:                    OrderLimit,
+				TimeInForce:             GoodTillCanceled,
+				Quantity:                "0.01000",
+				Price:                   "20000.00",
+				NewClientOrderID:        "my-order",
+				IcebergQty:              "0.002",
+				SelfTradePreventionMode: ExpireTaker,
+			},
+			false,
+		},
+		{
+			"market quantity",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", Sell).Market().Quantity(qty) },
+			NewOrderReq{Symbol: "BTCUSDT", Side: Sell, Type: OrderMarket, Quantity: "0.01000"},
+			false,
+		},
+		{
+			"market quote quantity",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Market().QuoteQuantity(Quantity{price.Decimal})
+			},
+			NewOrderReq{Symbol: "BTCUSDT", Side: Buy, Type: OrderMarket, QuoteOrderQty: "20000.00"},
+			false,
+		},
+		{
+			"stop loss",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", Sell).StopLoss().StopPrice(stop).Quantity(qty) },
+			NewOrderReq{Symbol: "BTCUSDT", Side: Sell, Type: OrderStopLoss, Quantity: "0.01000", StopPrice: "19500.00"},
+			false,
+		},
+		{
+			"stop loss limit",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Sell).StopLossLimit(stop).StopPrice(stop).Quantity(qty).TimeInForce(ImmediateOrCancel)
+			},
+			NewOrderReq{
+				Symbol:      "BTCUSDT",
+				Side:        Sell,
+				Type:        OrderStopLossLimit,
+				TimeInForce: ImmediateOrCancel,
+				Quantity:    "0.01000",
+				Price:       "19500.00",
+				StopPrice:   "19500.00",
+			},
+			false,
+		},
+		{
+			"limit maker",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", Buy).LimitMaker(price).Quantity(qty) },
+			NewOrderReq{Symbol: "BTCUSDT", Side: Buy, Type: OrderLimitMaker, Quantity: "0.01000", Price: "20000.00"},
+			false,
+		},
+		{
+			"no symbol",
+			func() *OrderBuilder { return NewOrderBuilder("", Buy).Market().Quantity(qty) },
+			NewOrderReq{},
+			true,
+		},
+		{
+			"invalid side",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", "HOLD").Market().Quantity(qty) },
+			NewOrderReq{},
+			true,
+		},
+		{
+			"no type",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", Buy).Quantity(qty) },
+			NewOrderReq{},
+			true,
+		},
+		{
+			"limit without time in force",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", Buy).Limit(price).Quantity(qty) },
+			NewOrderReq{},
+			true,
+		},
+		{
+			"limit with stop price",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Limit(price).StopPrice(stop).Quantity(qty).TimeInForce(GoodTillCanceled)
+			},
+			NewOrderReq{},
+			true,
+		},
+		{
+			"market with time in force",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Market().Quantity(qty).TimeInForce(FillOrKill)
+			},
+			NewOrderReq{},
+			true,
+		},
+		{
+			"stop loss without stop price",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", Sell).StopLoss().Quantity(qty) },
+			NewOrderReq{},
+			true,
+		},
+		{
+			"limit maker with time in force",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).LimitMaker(price).Quantity(qty).TimeInForce(GoodTillCanceled)
+			},
+			NewOrderReq{},
+			true,
+		},
+		{
+			"no quantity",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", Buy).Market() },
+			NewOrderReq{},
+			true,
+		},
+		{
+			"quantity and quote quantity",
+			func() *OrderBuilder { return NewOrderBuilder("BTCUSDT", Buy).Market().Quantity(qty).QuoteQuantity(qty) },
+			NewOrderReq{},
+			true,
+		},
+		{
+			"limit with quote quantity",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Limit(price).QuoteQuantity(qty).TimeInForce(GoodTillCanceled)
+			},
+			NewOrderReq{},
+			true,
+		},
+		{
+			"negative quantity",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Market().Quantity(Quantity{mustParseDecimal(t, "-1")})
+			},
+			NewOrderReq{},
+			true,
+		},
+		{
+			"market iceberg",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Market().Quantity(qty).IcebergQuantity(iceberg)
+			},
+			NewOrderReq{},
+			true,
+		},
+		{
+			"iceberg without good till canceled",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Limit(price).Quantity(qty).TimeInForce(ImmediateOrCancel).IcebergQuantity(iceberg)
+			},
+			NewOrderReq{},
+			true,
+		},
+		{
+			"iceberg not less than quantity",
+			func() *OrderBuilder {
+				return NewOrderBuilder("BTCUSDT", Buy).Limit(price).Quantity(iceberg).TimeInForce(GoodTillCanceled).IcebergQuantity(iceberg)
+			},
+			NewOrderReq{},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.build().Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OrderBuilder.Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidOrder) {
+				t.Errorf("OrderBuilder.Build() error = %v, want %v", err, ErrInvalidOrder)
+			}
+			if got != tt.want {
+				t.Errorf("OrderBuilder.Build() = \n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOrderReq_encode(t *testing.T) {
+	req := NewOrderReq{
+		Symbol:      "BTCUSDT",
+		Side:        Buy,
+		Type:        OrderLimit,
+		TimeInForce: GoodTillCanceled,
+		Quantity:    "0.01",
+		Price:       "20000",
+	}
+
+	got := url.Values{}
+	if err := schema.NewEncoder().Encode(req, got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := url.Values{
+		"symbol":      {"BTCUSDT"},
+		"side":        {"BUY"},
+		"type":        {"LIMIT"},
+		"timeInForce": {"GTC"},
+		"quantity":    {"0.01"},
+		"price":       {"20000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewOrderReq encoded = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"errors"
+	"time"
+)
+
+// TransientCodes are binance error codes which may succeed on retry:
+// unknown error, internal error and service shutting down.
+var TransientCodes = []int{-1000, -1001, -1016}
+
+// RetryPolicy retries requests which failed with a transient error code.
+// Other errors, including BackOffError, are returned immediately.
+type RetryPolicy struct {
+	// Codes which are retried. Nil uses TransientCodes.
+	Codes []int
+
+	// MaxRetries is the amount of retries after the first attempt.
+	MaxRetries int
+
+	// BackOff is the wait before the first retry,
+	// it doubles on each following retry.
+	BackOff time.Duration
+}
+
+// DefaultRetryPolicy retries transient errors 3 times,
+// waiting 500ms, 1s and 2s.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BackOff:    500 * time.Millisecond,
+}
+
+func (p *RetryPolicy) transient(code int) bool {
+	codes := p.Codes
+	if codes == nil {
+		codes = TransientCodes
+	}
+
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the wait before retrying after err,
+// or false if err should not be retried.
+// attempt counts the retries done so far.
+// A nil policy never retries.
+func (p *RetryPolicy) delay(err error, attempt int) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxRetries {
+		return 0, false
+	}
+
+	var re RequestError
+	if !errors.As(err, &re) || !p.transient(re.ErrorCode) {
+		return 0, false
+	}
+
+	return p.BackOff << attempt, true
+}
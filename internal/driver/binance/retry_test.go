@@ -0,0 +1,121 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_delay(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxRetries: 2,
+		BackOff:    time.Second,
+	}
+
+	transient := fmt.Errorf("binance: %w", RequestError{StatusCode: 500, ErrorCode: -1001})
+
+	tests := []struct {
+		name    string
+		policy  *RetryPolicy
+		err     error
+		attempt int
+		want    time.Duration
+		wantOK  bool
+	}{
+		{"nil policy", nil, transient, 0, 0, false},
+		{"no error", policy, nil, 0, 0, false},
+		{"first retry", policy, transient, 0, time.Second, true},
+		{"second retry", policy, transient, 1, 2 * time.Second, true},
+		{"max retries", policy, transient, 2, 0, false},
+		{"invalid symbol", policy, RequestError{StatusCode: 400, ErrorCode: -1121}, 0, 0, false},
+		{"back off", policy, BackOffError{StatusCode: 429, ErrorCode: -1000}, 0, 0, false},
+		{"other error", policy, errors.New("foo"), 0, 0, false},
+		{
+			"custom codes",
+			&RetryPolicy{Codes: []int{-1121}, MaxRetries: 1, BackOff: time.Second},
+			RequestError{StatusCode: 400, ErrorCode: -1121},
+			0,
+			time.Second,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.policy.delay(tt.err, tt.attempt)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("RetryPolicy.delay() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMarketData_GetJSON_retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"transient", -1001, 2, false},
+		{"invalid symbol", -1121, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					w.WriteHeader(http.StatusInternalServerError)
+					fmt.Fprintf(w, `{"code":%d,"msg":"error"}`, tt.code)
+					return
+				}
+				w.Write([]byte(`{}`))
+			}))
+			m.Retry = &RetryPolicy{MaxRetries: 3, BackOff: time.Millisecond}
+
+			err := m.GetJSON(testCTX, "/api/v3/ping", nil, &PingResp{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MarketData.GetJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("MarketData.GetJSON() calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMarketData_GetJSON_retryCanceled(t *testing.T) {
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"code":-1016,"msg":"This service is no longer available."}`))
+	}))
+	m.Retry = &RetryPolicy{MaxRetries: 3, BackOff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(testCTX, 100*time.Millisecond)
+	defer cancel()
+
+	if err := m.GetJSON(ctx, "/api/v3/ping", nil, &PingResp{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("MarketData.GetJSON() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
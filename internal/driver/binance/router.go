@@ -0,0 +1,97 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import "sync"
+
+// responseRouter correlates method responses with the requests awaiting them.
+// The zero value is ready for use.
+type responseRouter struct {
+	mtx    sync.Mutex
+	id     uint
+	chans  map[uint]chan<- wsMethodResponse
+	closed bool // no more requests are accepted
+}
+
+// add registers rc for the response of a new request.
+// ok is false when the router is closed.
+// When the id counter wraps around, ids which are still pending
+// and 0, which marks a message without id, are skipped.
+func (r *responseRouter) add(rc chan<- wsMethodResponse) (id uint, ok bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.closed {
+		return 0, false
+	}
+
+	if r.chans == nil {
+		r.chans = make(map[uint]chan<- wsMethodResponse)
+	}
+
+	for {
+		r.id++
+		if _, pending := r.chans[r.id]; r.id != 0 && !pending {
+			break
+		}
+	}
+	r.chans[r.id] = rc
+
+	return r.id, true
+}
+
+// pop removes and returns the response channel of id.
+func (r *responseRouter) pop(id uint) (rc chan<- wsMethodResponse, ok bool) {
+	r.mtx.Lock()
+	rc, ok = r.chans[id]
+	if ok {
+		delete(r.chans, id)
+	}
+	r.mtx.Unlock()
+
+	return rc, ok
+}
+
+// respond sends resp to the request with resp.ID.
+// It returns false when no such request is awaiting a response.
+// Response channels must have a buffer of 1, as only one response is sent.
+func (r *responseRouter) respond(resp wsMethodResponse) bool {
+	rc, ok := r.pop(resp.ID)
+	if ok {
+		rc <- resp
+	}
+	return ok
+}
+
+// close answers all pending requests with err
+// and refuses new requests.
+func (r *responseRouter) close(err error) {
+	r.mtx.Lock()
+	pending := r.chans
+	r.chans = nil
+	r.closed = true
+	r.mtx.Unlock()
+
+	for id, rc := range pending {
+		rc <- wsMethodResponse{
+			ID:    id,
+			Error: err,
+		}
+	}
+}
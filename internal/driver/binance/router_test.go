@@ -0,0 +1,146 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func Test_responseRouter(t *testing.T) {
+	var r responseRouter
+
+	rc := make(chan wsMethodResponse, 1)
+	id, ok := r.add(rc)
+	if !ok || id != 1 {
+		t.Fatalf("responseRouter.add() = %d, %v, want 1, true", id, ok)
+	}
+
+	tests := []struct {
+		name string
+		id   uint
+		want bool
+	}{
+		{"missing id", 2, false},
+		{"pending id", 1, true},
+		{"duplicate id", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.respond(wsMethodResponse{ID: tt.id, Result: true}); got != tt.want {
+				t.Errorf("responseRouter.respond() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := <-rc; got.ID != 1 || got.Result != true {
+		t.Errorf("responseRouter.respond() sent %v", got)
+	}
+}
+
+func Test_responseRouter_add_collision(t *testing.T) {
+	pending := make(chan wsMethodResponse, 1)
+	r := responseRouter{
+		id: ^uint(0) - 1, // about to wrap
+		chans: map[uint]chan<- wsMethodResponse{
+			^uint(0): pending,
+			1:        pending,
+		},
+	}
+
+	id, ok := r.add(make(chan wsMethodResponse, 1))
+	if !ok || id != 2 {
+		t.Errorf("responseRouter.add() = %d, %v, want 2, true", id, ok)
+	}
+	if r.chans[^uint(0)] != pending || r.chans[1] != pending {
+		t.Error("responseRouter.add() overwrote a pending channel")
+	}
+}
+
+func Test_responseRouter_concurrent(t *testing.T) {
+	const n = 100
+
+	var (
+		r   responseRouter
+		wg  sync.WaitGroup
+		ids = make(chan uint, n)
+		rcs = make([]chan wsMethodResponse, n)
+	)
+
+	for i := range rcs {
+		rcs[i] = make(chan wsMethodResponse, 1)
+
+		wg.Add(1)
+		go func(rc chan wsMethodResponse) {
+			defer wg.Done()
+
+			id, ok := r.add(rc)
+			if !ok {
+				t.Error("responseRouter.add() not ok")
+			}
+			ids <- id
+		}(rcs[i])
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[uint]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("responseRouter.add() duplicate id %d", id)
+		}
+		seen[id] = true
+
+		wg.Add(1)
+		go func(id uint) {
+			defer wg.Done()
+
+			if !r.respond(wsMethodResponse{ID: id}) {
+				t.Errorf("responseRouter.respond(%d) = false", id)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	for _, rc := range rcs {
+		if got := <-rc; !seen[got.ID] {
+			t.Errorf("responseRouter.respond() unexpected id %d", got.ID)
+		}
+	}
+}
+
+func Test_responseRouter_close(t *testing.T) {
+	var r responseRouter
+
+	rc := make(chan wsMethodResponse, 1)
+	id, _ := r.add(rc)
+
+	r.close(ErrStreamClosed)
+
+	if got := <-rc; got.ID != id || !errors.Is(got.Error, ErrStreamClosed) {
+		t.Errorf("responseRouter.close() sent %v", got)
+	}
+	if _, ok := r.add(make(chan wsMethodResponse, 1)); ok {
+		t.Error("responseRouter.add() ok after close")
+	}
+	if r.respond(wsMethodResponse{ID: id}) {
+		t.Error("responseRouter.respond() true after close")
+	}
+}
@@ -0,0 +1,76 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+// DefaultMaxClockOffset is the clock offset above which ClockDrifting reports true.
+// It leaves a margin within the default recvWindow of 5 seconds.
+const DefaultMaxClockOffset = time.Second
+
+// SyncTime measures the offset of the local clock to the server time.
+// The server time is compared to the middle of the request round trip.
+// A positive offset means the local clock is behind.
+// The offset is stored and can be retrieved with ClockOffset.
+func (m *MarketData) SyncTime(ctx context.Context) (time.Duration, error) {
+	var resp ServerTimeResp
+
+	clock := driver.ClockOrReal(m.Clock)
+
+	start := clock.Now()
+	if err := m.GetJSON(ctx, "/api/v3/time", nil, &resp); err != nil {
+		return 0, err
+	}
+	end := clock.Now()
+
+	local := start.Add(end.Sub(start) / 2)
+	offset := time.UnixMilli(resp.ServerTime).Sub(local)
+
+	atomic.StoreInt64(&m.clockOffset, int64(offset))
+	return offset, nil
+}
+
+// ClockOffset returns the offset measured by the last SyncTime call.
+func (m *MarketData) ClockOffset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&m.clockOffset))
+}
+
+// ClockDrifting reports if the absolute ClockOffset exceeds MaxClockOffset,
+// or DefaultMaxClockOffset when unset.
+// Signed requests risk rejection with error -1021, timestamp outside recvWindow,
+// and the host clock needs NTP attention.
+func (m *MarketData) ClockDrifting() bool {
+	limit := m.MaxClockOffset
+	if limit == 0 {
+		limit = DefaultMaxClockOffset
+	}
+
+	offset := m.ClockOffset()
+	if offset < 0 {
+		offset = -offset
+	}
+
+	return offset > limit
+}
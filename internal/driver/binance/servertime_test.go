@@ -0,0 +1,63 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMarketData_SyncTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		skew      time.Duration
+		max       time.Duration
+		wantDrift bool
+	}{
+		{"in sync", 0, 0, false},
+		{"ahead", 3 * time.Second, 0, true},
+		{"behind", -3 * time.Second, 0, true},
+		{"within max", 3 * time.Second, 5 * time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"serverTime":%d}`, time.Now().Add(tt.skew).UnixMilli())
+			}))
+			m.MaxClockOffset = tt.max
+
+			offset, err := m.SyncTime(testCTX)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d := offset - tt.skew; d < -100*time.Millisecond || d > 100*time.Millisecond {
+				t.Errorf("MarketData.SyncTime() = %s, want %s", offset, tt.skew)
+			}
+			if got := m.ClockOffset(); got != offset {
+				t.Errorf("MarketData.ClockOffset() = %s, want %s", got, offset)
+			}
+			if got := m.ClockDrifting(); got != tt.wantDrift {
+				t.Errorf("MarketData.ClockDrifting() = %v, want %v", got, tt.wantDrift)
+			}
+		})
+	}
+}
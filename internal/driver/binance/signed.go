@@ -0,0 +1,112 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+// APIKeyHeader carries the API key of signed requests.
+const APIKeyHeader = "X-MBX-APIKEY"
+
+// ErrNoCredentials is returned for signed requests
+// on a MarketData without APIKey or SecretKey.
+var ErrNoCredentials = errors.New("binance: APIKey and SecretKey required for signed requests")
+
+// Sign returns the hex encoded HMAC-SHA256 signature of payload with secretKey.
+func Sign(secretKey, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedPayload encodes values with a timestamp, and recvWindow when set,
+// and appends the signature of exactly that encoding.
+// The timestamp is corrected by the ClockOffset of SyncTime.
+func (m *MarketData) signedPayload(values url.Values) (string, error) {
+	if m.APIKey == "" || m.SecretKey == "" {
+		return "", ErrNoCredentials
+	}
+
+	signed := url.Values{}
+	for k, v := range values {
+		signed[k] = v
+	}
+
+	now := driver.ClockOrReal(m.Clock).Now().Add(m.ClockOffset())
+	signed.Set("timestamp", strconv.FormatInt(now.UnixMilli(), 10))
+	if m.RecvWindow > 0 {
+		signed.Set("recvWindow", strconv.FormatInt(m.RecvWindow.Milliseconds(), 10))
+	}
+
+	payload := signed.Encode()
+	return payload + "&signature=" + Sign(m.SecretKey, payload), nil
+}
+
+// signedJSON performs a single signed request attempt.
+func (m *MarketData) signedJSON(ctx context.Context, method, path string, weight int, values url.Values, target interface{}) error {
+	payload, err := m.signedPayload(values)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{APIKeyHeader: {m.APIKey}}
+	return m.doJSON(ctx, method, path, weight, payload, header, target)
+}
+
+// GetSignedWeight is like GetJSONWeight, for endpoints which require
+// a signature, such as account information.
+// A timestamp is added to data and the query is signed with SecretKey.
+// APIKey is sent in the APIKeyHeader.
+// Retried requests are signed again, with a new timestamp.
+func (m *MarketData) GetSignedWeight(ctx context.Context, path string, weight int, data, target interface{}) error {
+	values, err := m.encodeFormData(data)
+	if err != nil {
+		return fmt.Errorf("binance: %w", err)
+	}
+
+	return m.retry(ctx, func() error {
+		return m.signedJSON(ctx, http.MethodGet, path, weight, values, target)
+	})
+}
+
+// PostSignedWeight performs a signed POST request, for endpoints such as placing orders.
+// data is encoded as form in the request body, which is signed
+// like the query of GetSignedWeight.
+// The response is handled like GetJSON, including back-off and the Limiter.
+// As a failed POST may still have been executed, it is neither retried
+// nor sent to fallback hosts.
+func (m *MarketData) PostSignedWeight(ctx context.Context, path string, weight int, data, target interface{}) error {
+	values, err := m.encodeFormData(data)
+	if err != nil {
+		return fmt.Errorf("binance: %w", err)
+	}
+
+	return m.signedJSON(ctx, http.MethodPost, path, weight, values, target)
+}
@@ -0,0 +1,191 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+// Example of the binance API documentation, SIGNED endpoint examples.
+const (
+	testAPIKey    = "vmPUZE6mv9SD5VNHk4HlWFsOr6aKE2zvsw0MuIgwCIPy6utIco14y7Ju91duEh8A"
+	testSecretKey = "NhqPtmdSJYdKjVHjA7PZj4Mge3R5YNiP1e3UZjInClVN65XAbvqqM6A7H5fATj0j"
+)
+
+func TestSign(t *testing.T) {
+	const (
+		payload = "symbol=LTCBTC&side=BUY&type=LIMIT&timeInForce=GTC&quantity=1&price=0.1&recvWindow=5000&timestamp=1499827319559"
+		want    = "c8db56825ae71d6d79447849e617115f4a920fa2acdcab2b053c4b2838bd6b71"
+	)
+
+	if got := Sign(testSecretKey, payload); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+type testOrderParams struct {
+	Symbol      string `schema:"symbol"`
+	Side        string `schema:"side"`
+	Type        string `schema:"type"`
+	TimeInForce string `schema:"timeInForce"`
+	Quantity    string `schema:"quantity"`
+	Price       string `schema:"price"`
+}
+
+var testOrder = testOrderParams{"LTCBTC", "BUY", "LIMIT", "GTC", "1", "0.1"}
+
+type signedRequest struct {
+	method      string
+	apiKey      string
+	contentType string
+	query       string
+	body        string
+}
+
+func newTestSignedMarketData(t *testing.T) (*MarketData, *[]signedRequest) {
+	var reqs []signedRequest
+
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		reqs = append(reqs, signedRequest{
+			r.Method,
+			r.Header.Get(APIKeyHeader),
+			r.Header.Get("Content-Type"),
+			r.URL.RawQuery,
+			string(body),
+		})
+		w.Write([]byte(`{}`))
+	}))
+
+	m.APIKey, m.SecretKey = testAPIKey, testSecretKey
+	m.RecvWindow = 5 * time.Second
+	m.Clock = driver.NewFakeClock(time.UnixMilli(1499827319559))
+
+	return m, &reqs
+}
+
+// Values are encoded in key order, the signature is appended last.
+const testSignedPayload = "price=0.1&quantity=1&recvWindow=5000&side=BUY&symbol=LTCBTC&timeInForce=GTC&timestamp=1499827319559&type=LIMIT" +
+	"&signature=70fd30433bc3a2e3b5ff17d075e50538dde3734841da6dc28d79113dd37fa9c7"
+
+func TestMarketData_GetSignedWeight(t *testing.T) {
+	m, reqs := newTestSignedMarketData(t)
+
+	if err := m.GetSignedWeight(testCTX, "/api/v3/order", 2, testOrder, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := signedRequest{http.MethodGet, testAPIKey, "", testSignedPayload, ""}
+	if len(*reqs) != 1 || (*reqs)[0] != want {
+		t.Fatalf("MarketData.GetSignedWeight() requests = %v, want %v", *reqs, want)
+	}
+
+	// The server verifies the signature over the query without it.
+	query := (*reqs)[0].query
+	i := strings.LastIndex(query, "&signature=")
+	if got := Sign(testSecretKey, query[:i]); got != query[i+len("&signature="):] {
+		t.Errorf("signature %s does not match the query", query[i+len("&signature="):])
+	}
+}
+
+func TestMarketData_PostSignedWeight(t *testing.T) {
+	m, reqs := newTestSignedMarketData(t)
+
+	if err := m.PostSignedWeight(testCTX, "/api/v3/order/test", 1, testOrder, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := signedRequest{http.MethodPost, testAPIKey, formContentType, "", testSignedPayload}
+	if len(*reqs) != 1 || (*reqs)[0] != want {
+		t.Errorf("MarketData.PostSignedWeight() requests = %v, want %v", *reqs, want)
+	}
+}
+
+func TestMarketData_signedPayload(t *testing.T) {
+	m, _ := newTestSignedMarketData(t)
+	m.RecvWindow = 0
+	m.clockOffset = int64(time.Second)
+
+	got, err := m.signedPayload(url.Values{"symbol": {"LTCBTC"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The local clock is corrected by the offset of SyncTime.
+	const payload = "symbol=LTCBTC&timestamp=1499827320559"
+	if want := payload + "&signature=" + Sign(testSecretKey, payload); got != want {
+		t.Errorf("MarketData.signedPayload() = %s, want %s", got, want)
+	}
+}
+
+func TestMarketData_GetSignedWeight_noCredentials(t *testing.T) {
+	m, reqs := newTestSignedMarketData(t)
+	m.SecretKey = ""
+
+	err := m.GetSignedWeight(testCTX, "/api/v3/account", 10, nil, &struct{}{})
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("MarketData.GetSignedWeight() error = %v, want %v", err, ErrNoCredentials)
+	}
+	if len(*reqs) != 0 {
+		t.Errorf("MarketData.GetSignedWeight() sent %d requests", len(*reqs))
+	}
+}
+
+func TestMarketData_PostSignedWeight_noFallback(t *testing.T) {
+	var calls int
+	m := newTestMarketData(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	m.Hosts = append(m.Hosts, m.Hosts[0])
+	m.APIKey, m.SecretKey = testAPIKey, testSecretKey
+	m.Retry = &RetryPolicy{Codes: TransientCodes, MaxRetries: 3}
+
+	err := m.PostSignedWeight(testCTX, "/api/v3/order", 1, testOrder, &struct{}{})
+
+	var re RequestError
+	if !errors.As(err, &re) || re.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("MarketData.PostSignedWeight() error = %v, want status %d", err, http.StatusServiceUnavailable)
+	}
+	// The order may have been placed, so it is not sent again.
+	if calls != 1 {
+		t.Errorf("MarketData.PostSignedWeight() calls = %d, want 1", calls)
+	}
+}
+
+func TestMarketData_doJSON_method(t *testing.T) {
+	m, reqs := newTestSignedMarketData(t)
+
+	err := m.doJSON(testCTX, http.MethodDelete, "/api/v3/order", 1, "orderId=1", nil, &struct{}{})
+	if err == nil || len(*reqs) != 0 {
+		t.Errorf("MarketData.doJSON(DELETE) error = %v, requests %d, want unsupported method", err, len(*reqs))
+	}
+}
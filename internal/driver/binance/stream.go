@@ -19,11 +19,18 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package binance
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/muhlemmer/yatgo/internal/driver"
@@ -32,14 +39,17 @@ import (
 )
 
 type wsMethodError struct {
-	Code int
-	Msg  string
+	ErrorCode int    `json:"code"`
+	ErrorMsg  string `json:"msg"`
 }
 
 func (e wsMethodError) Error() string {
-	return fmt.Sprintf("binance websocket response code %d, %s", e.Code, e.Msg)
+	return fmt.Sprintf("binance websocket response code %d, %s", e.ErrorCode, e.ErrorMsg)
 }
 
+func (e wsMethodError) Code() int       { return e.ErrorCode }
+func (e wsMethodError) Message() string { return e.ErrorMsg }
+
 type wsMethodResponse struct {
 	ID     uint
 	Result interface{}
@@ -61,14 +71,60 @@ type Stream struct {
 	cancel context.CancelFunc
 
 	conn     *websocket.Conn
-	handlers driver.SyncMap[string, driver.JSONHandler]
+	info     ConnInfo
+	handlers driver.SyncMap[string, driver.ContextHandler]
 	wg       sync.WaitGroup
 
+	closingPrices  driver.SyncMap[string, driver.ClosingPrice] // by kline stream name
+	decodeFailures driver.SyncMap[string, *int64]              // atomic counts by stream name
+
+	writeTimeout time.Duration
+	readTimeout  time.Duration
+	panicPolicy  PanicPolicy
+	panicHook    PanicHook
+
 	queue  chan wsMethodRequest
-	qlimit ratelimit.Limiter
-	qmtx   sync.Mutex
-	qid    uint
-	qrc    map[uint]chan<- wsMethodResponse
+	qlimit limiter
+	router responseRouter
+
+	cmtx     sync.Mutex
+	closeErr error
+	readErr  error // that ended listen
+
+	clock    driver.Clock
+	lastRead int64 // atomic, unix nano time of the last message, ping or pong
+}
+
+// ConnInfo holds diagnostic metadata of the websocket connection,
+// captured when the connection is established.
+type ConnInfo struct {
+	RemoteAddr  string // Address of the binance edge node
+	LocalAddr   string
+	Subprotocol string // Negotiated subprotocol, empty if none
+	Compression bool   // permessage-deflate was negotiated
+}
+
+func newConnInfo(conn *websocket.Conn, resp *http.Response) ConnInfo {
+	info := ConnInfo{
+		RemoteAddr:  conn.RemoteAddr().String(),
+		LocalAddr:   conn.LocalAddr().String(),
+		Subprotocol: conn.Subprotocol(),
+	}
+
+	if resp != nil {
+		for _, ext := range resp.Header.Values("Sec-Websocket-Extensions") {
+			if strings.Contains(ext, "permessage-deflate") {
+				info.Compression = true
+			}
+		}
+	}
+
+	return info
+}
+
+// ConnInfo returns the metadata of the underlying websocket connection.
+func (s *Stream) ConnInfo() ConnInfo {
+	return s.info
 }
 
 type streamMessage struct {
@@ -83,58 +139,266 @@ type streamMessage struct {
 	Data   json.RawMessage `json:"data,omitempty"`
 }
 
+// extendReadDeadline moves the read deadline readTimeout ahead.
+// It is called on every received message, ping and pong.
+func (s *Stream) extendReadDeadline() error {
+	if s.readTimeout <= 0 {
+		return nil
+	}
+	return s.conn.SetReadDeadline(time.Now().Add(s.readTimeout))
+}
+
+func (s *Stream) touch() {
+	atomic.StoreInt64(&s.lastRead, s.clock.Now().UnixNano())
+}
+
+// LastMessage returns the time the last message, ping or pong was received,
+// or the time of connecting when nothing was received yet.
+func (s *Stream) LastMessage() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&s.lastRead))
+}
+
+// handlePing extends the read deadline and replies with a pong,
+// like the default ping handler does.
+func (s *Stream) handlePing(data string) error {
+	s.touch()
+	if err := s.extendReadDeadline(); err != nil {
+		return err
+	}
+
+	err := s.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	if err == websocket.ErrCloseSent {
+		return nil
+	}
+	return err
+}
+
 func (s *Stream) listen() {
 	defer s.wg.Done()
 	defer s.cancel()
 
+	s.conn.SetPingHandler(s.handlePing)
+	s.conn.SetPongHandler(func(string) error {
+		s.touch()
+		return s.extendReadDeadline()
+	})
+
 	for {
-		_, data, err := s.conn.ReadMessage()
+		if err := s.extendReadDeadline(); err != nil {
+			zerolog.Ctx(s.ctx).Err(err).Msg("websocket read deadline")
+			return
+		}
+
+		buf, err := s.readMessage()
 		if err != nil {
 			zerolog.Ctx(s.ctx).Err(err).Msg("websocket receive")
+
+			s.cmtx.Lock()
+			s.readErr = err
+			s.cmtx.Unlock()
 			return
 		}
+		s.touch()
 
 		s.wg.Add(1)
-		go s.dispatch(data)
+		if isControlMessage(buf.Bytes()) {
+			s.dispatchBuffer(buf, time.Now())
+		} else {
+			go s.dispatchBuffer(buf, time.Now())
+		}
+	}
+}
+
+// readBuffers pools the buffers messages are read into.
+// Handlers never see these buffers: dispatch decodes the event data
+// into a json.RawMessage, which is a copy. So handlers own their data,
+// as documented by driver.JSONHandler.
+var readBuffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// maxPooledBuffer is the capacity above which read buffers
+// are left to the garbage collector, so that a single large
+// message does not keep its memory alive in the pool.
+const maxPooledBuffer = 1 << 20
+
+func putReadBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		readBuffers.Put(buf)
+	}
+}
+
+// readMessage reads the next message into a pooled buffer.
+func (s *Stream) readMessage() (*bytes.Buffer, error) {
+	_, r, err := s.conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := readBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	if _, err = buf.ReadFrom(r); err != nil {
+		putReadBuffer(buf)
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// dispatchBuffer dispatches the message in buf and returns buf to the pool.
+func (s *Stream) dispatchBuffer(buf *bytes.Buffer, received time.Time) {
+	defer putReadBuffer(buf)
+	s.dispatch(buf.Bytes(), received)
+}
+
+// maxControlMessage is the size up to which messages
+// without a stream name are dispatched on the reader goroutine.
+const maxControlMessage = 128
+
+// isControlMessage reports if data is a small message which is not a stream event,
+// such as a method response or an empty frame.
+// Those are dispatched without calling a handler,
+// so it is cheaper to do so inline than to spawn a goroutine.
+func isControlMessage(data []byte) bool {
+	return len(data) <= maxControlMessage && !bytes.Contains(data, []byte(`"stream"`))
+}
+
+// Subscriptions returns the names of the streams with a registered handler, sorted.
+// It does not send a LIST_SUBSCRIPTIONS request.
+func (s *Stream) Subscriptions() []string {
+	var streams []string
+
+	s.handlers.Range(func(stream string, _ driver.ContextHandler) bool {
+		streams = append(streams, stream)
+		return true
+	})
+	sort.Strings(streams)
+
+	return streams
+}
+
+// HandlerFor returns the handler registered for stream,
+// as it was passed to Subscribe, SubscribeContext or SubscribeRaw.
+// Typed subscriptions like SubscribeKlines return the internal decoding handler.
+func (s *Stream) HandlerFor(stream string) (handler interface{}, ok bool) {
+	h, ok := s.handlers.Load(stream)
+	if !ok {
+		return nil, false
 	}
+
+	switch h := h.(type) {
+	case interface{ Unwrap() driver.JSONHandler }:
+		return h.Unwrap(), true
+	case rawHandler:
+		if jh, ok := h.h.(jsonEventHandler); ok {
+			return jh.h, true
+		}
+		return h.h, true
+	}
+
+	return h, true
 }
 
-func (s *Stream) popResponseChan(id uint) (rc chan<- wsMethodResponse, ok bool) {
-	s.qmtx.Lock()
-	rc, ok = s.qrc[id]
-	if ok {
-		delete(s.qrc, id)
+// ErrInvalidMessage is returned for stream messages which are
+// incomplete or otherwise invalid JSON.
+var ErrInvalidMessage = errors.New("binance: invalid stream message")
+
+// ErrNotCombined is logged for event messages without the
+// {"stream","data"} envelope, which happens when the combined
+// property of the connection was set to false.
+// Such events can not be routed to a handler and are dropped.
+var ErrNotCombined = errors.New(`binance: event without stream envelope, is the "combined" property false?`)
+
+// maxLogData is the amount of bytes of an invalid message that gets logged.
+const maxLogData = 256
+
+func truncate(data []byte, n int) []byte {
+	if len(data) > n {
+		return data[:n]
 	}
-	s.qmtx.Unlock()
+	return data
+}
 
-	return rc, ok
+func parseStreamMessage(data []byte) (msg streamMessage, err error) {
+	if err = json.Unmarshal(data, &msg); err != nil {
+		return msg, fmt.Errorf("%w (%d bytes): %v", ErrInvalidMessage, len(data), err)
+	}
+
+	return msg, nil
+}
+
+// decodeError is the panic value of typed handlers,
+// like the one of SubscribeKlines, which fail to decode an event.
+type decodeError struct {
+	err error
+}
+
+func (e decodeError) Error() string { return e.err.Error() }
+func (e decodeError) Unwrap() error { return e.err }
+
+func (s *Stream) countDecodeFailure(stream string) {
+	n, _ := s.decodeFailures.LoadOrStore(stream, new(int64))
+	atomic.AddInt64(n, 1)
 }
 
-func (s *Stream) dispatch(data []byte) {
+// DecodeFailures returns the amount of events per stream
+// which typed handlers failed to decode.
+// A growing count for a stream suggests its event schema changed.
+// Decode failures are recovered according to the PanicPolicy.
+func (s *Stream) DecodeFailures() map[string]int64 {
+	counts := make(map[string]int64)
+	s.decodeFailures.Range(func(stream string, n *int64) bool {
+		counts[stream] = atomic.LoadInt64(n)
+		return true
+	})
+	return counts
+}
+
+// dispatch routes data to its handler.
+// The received time is passed in the handler's context,
+// see driver.Received.
+func (s *Stream) dispatch(data []byte, received time.Time) {
 	defer s.wg.Done()
 
+	msg, err := parseStreamMessage(data)
+	if err != nil {
+		zerolog.Ctx(s.ctx).Err(err).Bytes("data", truncate(data, maxLogData)).Msg("dispatch")
+		return
+	}
+
 	logger := zerolog.Ctx(s.ctx).With().RawJSON("data", data).Logger()
 	logger.Debug().Msg("")
 
 	defer func() {
 		x := recover()
-		if x != nil {
-			err, _ := x.(error)
-			if err == nil {
-				logger.Panic().Interface("value", x).Msg("re-panic in dispatch recover")
-				return
-			}
+		if x == nil {
+			return
+		}
 
-			logger.Err(err).Msg("dispatch panic recover")
+		if err, ok := x.(error); ok && errors.As(err, new(decodeError)) {
+			s.countDecodeFailure(msg.Stream)
 		}
-	}()
 
-	var msg streamMessage
-	err := json.Unmarshal(data, &msg)
+		switch s.panicPolicy {
+		case Propagate:
+			panic(x)
+		case RecoverAndNotify:
+			logger.Error().Interface("value", x).Msg("dispatch panic recover")
+			if s.panicHook != nil {
+				s.panicHook(msg.Stream, x)
+			}
+			return
+		}
 
-	if err != nil {
-		panic(fmt.Errorf("stream.dispatch: %w", err))
-	}
+		err, _ := x.(error)
+		if err == nil {
+			logger.Panic().Interface("value", x).Msg("re-panic in dispatch recover")
+			return
+		}
+
+		logger.Err(err).Msg("dispatch panic recover")
+	}()
 
 	if msg.Error != nil {
 		if msg.ID != 0 {
@@ -147,82 +411,203 @@ func (s *Stream) dispatch(data []byte) {
 	}
 
 	if msg.ID != 0 {
-		if c, ok := s.popResponseChan(msg.ID); ok {
-			c <- wsMethodResponse{
-				ID:     msg.ID,
-				Result: msg.Result,
-			}
-		} else {
+		if !s.router.respond(wsMethodResponse{ID: msg.ID, Result: msg.Result}) {
 			logger.Warn().Msg("unknown request ID in method response dispatch")
 		}
 		return
 	}
 
-	if msg.Stream != "" {
-		if handler, ok := s.handlers.Load(msg.Stream); ok {
-			handler.Event(msg.Data)
-			return
-		}
+	if msg.Stream == "" && len(msg.Data) == 0 {
+		logger.Err(ErrNotCombined).Msg("dispatch")
+		return
+	}
+
+	if handler, ok := s.handlers.Load(msg.Stream); ok {
+		handler.Event(driver.WithReceived(s.ctx, received), msg.Data)
+		return
 	}
 
 	logger.Warn().Msg("unhandeled message in dispatch")
 }
 
-func (s *Stream) addReponseChan(rc chan<- wsMethodResponse) (id uint) {
-	s.qmtx.Lock()
-	defer s.qmtx.Unlock()
+func (s *Stream) addQueue(msg wsMethodRequest) <-chan wsMethodResponse {
+	_, rc := s.enqueue(msg)
+	return rc
+}
+
+// enqueue is like addQueue, but also returns the assigned request ID.
+func (s *Stream) enqueue(msg wsMethodRequest) (uint, <-chan wsMethodResponse) {
+	rc := make(chan wsMethodResponse, 1)
+
+	if err := s.ctx.Err(); err != nil {
+		rc <- wsMethodResponse{Error: streamClosedError{err}}
+		return 0, rc
+	}
+
+	id, ok := s.router.add(rc)
+	if !ok {
+		rc <- wsMethodResponse{Error: streamClosedError{s.ctx.Err()}}
+		return 0, rc
+	}
+	msg.ID = id
 
-	if s.qrc == nil {
-		s.qrc = make(map[uint]chan<- wsMethodResponse)
+	// When the stream closes before the message is sent,
+	// close answers rc with an error.
+	select {
+	case s.queue <- msg:
+	case <-s.ctx.Done():
 	}
 
-	s.qid++
-	s.qrc[s.qid] = rc
+	return msg.ID, rc
+}
 
-	return s.qid
+// UnexpectedResponseError is returned when a method response
+// is not the confirmation expected for the request.
+type UnexpectedResponseError struct {
+	Method     string
+	RequestID  uint
+	ResponseID uint
+	Result     interface{}
 }
 
-func (s *Stream) addQueue(msg wsMethodRequest) <-chan wsMethodResponse {
-	rc := make(chan wsMethodResponse, 1)
+func (e *UnexpectedResponseError) Error() string {
+	return fmt.Sprintf("binance: unexpected %s response id %d for request id %d, result: %v", e.Method, e.ResponseID, e.RequestID, e.Result)
+}
 
-	if s.ctx.Err() != nil {
-		rc <- wsMethodResponse{Error: websocket.ErrCloseSent}
-		return rc
+// checkConfirmation verifies resp is a successful confirmation of
+// the request with reqID, which binance sends as `{"result":null,"id":N}`.
+func checkConfirmation(method string, reqID uint, resp wsMethodResponse) error {
+	if resp.Error != nil {
+		return resp.Error
 	}
 
-	msg.ID = s.addReponseChan(rc)
+	if resp.ID != reqID || resp.Result != nil {
+		return &UnexpectedResponseError{
+			Method:     method,
+			RequestID:  reqID,
+			ResponseID: resp.ID,
+			Result:     resp.Result,
+		}
+	}
 
-	s.queue <- msg
-	return rc
+	return nil
 }
 
 func (s *Stream) sendErrResponse(reqID uint, err error) {
-	rc, ok := s.popResponseChan(reqID)
+	s.router.respond(wsMethodResponse{ID: reqID, Error: err})
+}
 
-	if ok {
-		rc <- wsMethodResponse{
-			ID:    reqID,
-			Error: err,
-		}
+// ErrStreamClosed is returned by methods called on a closed Stream.
+// Use errors.Is to match it, the cause of closing is wrapped.
+var ErrStreamClosed = errors.New("binance: stream closed")
+
+type streamClosedError struct {
+	cause error
+}
+
+func (e streamClosedError) Error() string {
+	if e.cause == nil {
+		return ErrStreamClosed.Error()
 	}
+	return fmt.Sprintf("%v: %v", ErrStreamClosed, e.cause)
+}
+
+func (e streamClosedError) Is(target error) bool { return target == ErrStreamClosed }
+func (e streamClosedError) Unwrap() error        { return e.cause }
+
+// CloseError reports an unclean close of a Stream.
+type CloseError struct {
+	// Frame is the close frame sent by the server, if any.
+	// For example, code 1008 (policy violation) when too many streams are subscribed,
+	// which should not be blindly retried.
+	Frame *websocket.CloseError
+
+	Conn   error                  // Error of closing the connection
+	Panics map[string]interface{} // Recovered panics of handler Done calls, by stream
+}
+
+func (e *CloseError) Error() string {
+	var msgs []string
+	if e.Frame != nil {
+		msgs = append(msgs, e.Frame.Error())
+	}
+	if e.Conn != nil {
+		msgs = append(msgs, e.Conn.Error())
+	}
+
+	streams := make([]string, 0, len(e.Panics))
+	for stream := range e.Panics {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+
+	for _, stream := range streams {
+		msgs = append(msgs, fmt.Sprintf("%s Done panic: %v", stream, e.Panics[stream]))
+	}
+
+	return "binance: stream close: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns Frame, or Conn if there is no Frame.
+func (e *CloseError) Unwrap() error {
+	if e.Frame != nil {
+		return e.Frame
+	}
+	return e.Conn
+}
+
+// CloseErr returns a *CloseError when the Stream did not close cleanly.
+// It returns nil while the Stream is open, or after a clean close.
+func (s *Stream) CloseErr() error {
+	s.cmtx.Lock()
+	defer s.cmtx.Unlock()
+
+	return s.closeErr
+}
+
+// handlerDone calls handler.Done and returns the recovered panic, if any.
+func (s *Stream) handlerDone(stream string, handler driver.ContextHandler) (x interface{}) {
+	defer func() {
+		if x = recover(); x != nil {
+			zerolog.Ctx(s.ctx).Error().Str("stream", stream).Interface("value", x).Msg("handler Done panic recover")
+		}
+	}()
+
+	handler.Done(s.ctx)
+	return nil
 }
 
 func (s *Stream) close() {
 	s.cancel()
-	close(s.queue)
 
 	err := s.conn.Close()
 	zerolog.Ctx(s.ctx).Err(err).Msg("stream closed")
 
-	// drain the channel
-	for msg := range s.queue {
-		s.sendErrResponse(msg.ID, err)
-	}
+	// Fail queued requests and requests which are still awaiting a response.
+	// The queue channel is never closed, as concurrent senders would panic.
+	s.router.close(streamClosedError{err})
+
+	var panics map[string]interface{}
 
-	s.handlers.Range(func(_ string, handler driver.JSONHandler) bool {
-		handler.Done()
+	s.handlers.Range(func(stream string, handler driver.ContextHandler) bool {
+		if x := s.handlerDone(stream, handler); x != nil {
+			if panics == nil {
+				panics = make(map[string]interface{})
+			}
+			panics[stream] = x
+		}
 		return true
 	})
+
+	s.cmtx.Lock()
+	defer s.cmtx.Unlock()
+
+	var frame *websocket.CloseError
+	errors.As(s.readErr, &frame)
+
+	if frame != nil || err != nil || panics != nil {
+		s.closeErr = &CloseError{Frame: frame, Conn: err, Panics: panics}
+	}
 }
 
 func (s *Stream) sendQueue() {
@@ -243,7 +628,12 @@ work:
 				break work
 			}
 
-			err = s.conn.WriteJSON(msg)
+			if s.writeTimeout > 0 {
+				err = s.conn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+			}
+			if err == nil {
+				err = s.conn.WriteJSON(msg)
+			}
 			zerolog.Ctx(s.ctx).Err(err).Interface("msg", msg).Msg("websocket send")
 
 			if err != nil {
@@ -257,35 +647,104 @@ work:
 	s.close()
 }
 
-var newStreamLimiter = ratelimit.New(5)
+// limiter blocks until the next operation is allowed.
+// It is satisfied by ratelimit.Limiter.
+type limiter interface {
+	Take() time.Time
+}
+
+// noLimit is a limiter which never blocks.
+type noLimit struct{}
+
+func (noLimit) Take() time.Time { return time.Now() }
+
+var newStreamLimiter limiter = ratelimit.New(5)
+
+var (
+	jitterMtx  sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// jitter returns a random duration in [0, max).
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	jitterMtx.Lock()
+	defer jitterMtx.Unlock()
+
+	return time.Duration(jitterRand.Int63n(int64(max)))
+}
 
 // NewStream dails the websocket endpoint for binance combined streams.
 // The returned stream is closed when the context is canceled.
 // On any error, the stream closes and terminates.
 // Calling methods on the Stream after closingwill results in errors to be returned.
-func NewStream(ctx context.Context) (*Stream, error) {
+func NewStream(ctx context.Context, opts ...StreamOption) (*Stream, error) {
+	cfg := newStreamConfig(opts)
+
 	logger := zerolog.Ctx(ctx).With().Str("driver", "binance").Str("obj", "Stream").Logger()
 	ctx = logger.WithContext(ctx)
 
-	newStreamLimiter.Take()
+	cfg.openLimiter.Take()
+
+	if cfg.sendLimiter == nil {
+		cfg.sendLimiter = ratelimit.New(5)
+	}
+
+	if d := jitter(cfg.openJitter); d > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("binance.NewStream: %w", ctx.Err())
+		case <-cfg.clock.After(d):
+		}
+	}
 
-	conn, err := driver.DialWebsocket(ctx, websocket.DefaultDialer, EndpointWsStream, nil)
+	conn, resp, err := driver.DialWebsocket(ctx, cfg.dialer, cfg.endpoint(), cfg.header)
 	if err != nil {
 		return nil, fmt.Errorf("binance.NewStream: %w", err)
 	}
 
 	s := &Stream{
-		conn:   conn,
-		queue:  make(chan wsMethodRequest, 64),
-		qlimit: ratelimit.New(5),
+		conn:         conn,
+		info:         newConnInfo(conn, resp),
+		writeTimeout: cfg.writeTimeout,
+		readTimeout:  cfg.readTimeout,
+		panicPolicy:  cfg.panicPolicy,
+		panicHook:    cfg.panicHook,
+		clock:        cfg.clock,
+		queue:        make(chan wsMethodRequest, cfg.queueSize),
+		qlimit:       cfg.sendLimiter,
 	}
 
 	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.touch()
+
+	if cfg.subsInURL {
+		// Already subscribed by the URL,
+		// handlers must be in place before the first event is read.
+		for _, sub := range cfg.subs {
+			if _, loaded := s.handlers.LoadOrStore(sub.stream, sub.handler); loaded {
+				s.cancel()
+				conn.Close()
+				return nil, fmt.Errorf("binance.NewStream: %w: %s", ErrStreamSubscribed, sub.stream)
+			}
+		}
+	}
 
 	s.wg.Add(2)
 	go s.listen()
 	go s.sendQueue()
 
+	if len(cfg.subs) > 0 && !cfg.subsInURL {
+		if err = s.subscribe(cfg.subs); err != nil {
+			s.cancel()
+			s.wg.Wait()
+			return nil, fmt.Errorf("binance.NewStream: %w", err)
+		}
+	}
+
 	return s, nil
 }
 
@@ -301,21 +760,180 @@ var (
 // The channel can be buffered with the size of bufLen,
 // to accomodate for short bursts of data.
 func (s *Stream) Subscribe(stream string, handler driver.JSONHandler) error {
-	if _, loaded := s.handlers.LoadOrStore(stream, handler); loaded {
-		return ErrStreamSubscribed
+	if err := s.subscribeRaw([]string{stream}, jsonEventHandler{handler}); err != nil {
+		return fmt.Errorf("stream.Subscribe: %w", err)
 	}
 
-	resp := <-s.addQueue(wsMethodRequest{
+	return nil
+}
+
+// SubscribeTimed is like Subscribe, and returns the confirmation latency:
+// the time from queuing the SUBSCRIBE request until its confirmation is received.
+// It includes the wait for the send rate limit, but not any handler time,
+// so a slow confirmation points at the network or the server.
+func (s *Stream) SubscribeTimed(stream string, handler driver.JSONHandler) (time.Duration, error) {
+	refs := int32(1)
+
+	latency, err := s.subscribeTimed([]subscription{
+		{stream, rawHandler{stream, jsonEventHandler{handler}, &refs}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("stream.SubscribeTimed: %w", err)
+	}
+
+	return latency, nil
+}
+
+// SubscribeContext is like Subscribe, but handler receives the Stream's context,
+// which is canceled when the Stream closes.
+func (s *Stream) SubscribeContext(stream string, handler driver.ContextHandler) error {
+	if err := s.subscribe([]subscription{{stream, handler}}); err != nil {
+		return fmt.Errorf("stream.SubscribeContext: %w", err)
+	}
+
+	return nil
+}
+
+// StreamEventHandler receives events of multiple streams.
+// Contrary to JSONHandler, the stream name is passed with each event,
+// so that a single handler can route different stream types.
+type StreamEventHandler interface {
+	// Event is called on each message of any of the subscribed streams.
+	Event(stream string, data []byte)
+
+	// Done is called once, after all subscribed streams are closed or unsubscribed.
+	Done()
+}
+
+// rawHandler adapts a shared StreamEventHandler for a single stream.
+type rawHandler struct {
+	stream string
+	h      StreamEventHandler
+	refs   *int32 // Streams still using h
+}
+
+func (r rawHandler) Event(ctx context.Context, data []byte) {
+	if jh, ok := r.h.(jsonEventHandler); ok {
+		driver.TimedEvent(ctx, jh.h, data)
+		return
+	}
+	r.h.Event(r.stream, data)
+}
+
+func (r rawHandler) Done(context.Context) {
+	if atomic.AddInt32(r.refs, -1) == 0 {
+		r.h.Done()
+	}
+}
+
+// SubscribeRaw subscribes handler to all streams using a single request.
+// Events can be routed by the suffix of the stream name:
+//
+//	switch {
+//	case strings.HasSuffix(stream, "@aggTrade"):
+//	case strings.HasSuffix(stream, "@bookTicker"):
+//	case strings.Contains(stream, "@kline_"):
+//	}
+//
+// Unsubscribing a stream stops its events,
+// Done is called when none of the streams remain subscribed.
+// The typed subscriptions, like SubscribeKlines, are built on SubscribeRaw.
+// ErrNoStreams is returned when streams is empty.
+func (s *Stream) SubscribeRaw(streams []string, handler StreamEventHandler) error {
+	if err := s.subscribeRaw(streams, handler); err != nil {
+		return fmt.Errorf("stream.SubscribeRaw: %w", err)
+	}
+
+	return nil
+}
+
+// ErrNoStreams is returned when subscribing to an empty list of streams.
+var ErrNoStreams = errors.New("binance: no streams to subscribe")
+
+func (s *Stream) subscribeRaw(streams []string, handler StreamEventHandler) error {
+	if len(streams) == 0 {
+		return ErrNoStreams
+	}
+
+	refs := int32(len(streams))
+	subs := make([]subscription, len(streams))
+
+	for i, stream := range streams {
+		subs[i] = subscription{stream, rawHandler{stream, handler, &refs}}
+	}
+
+	return s.subscribe(subs)
+}
+
+// jsonEventHandler adapts a JSONHandler, which is not interested in the stream name.
+type jsonEventHandler struct {
+	h driver.JSONHandler
+}
+
+func (j jsonEventHandler) Event(_ string, data []byte) { j.h.Event(data) }
+func (j jsonEventHandler) Done()                       { j.h.Done() }
+
+type subscription struct {
+	stream  string
+	handler driver.ContextHandler
+}
+
+// subscribe to all streams with a single SUBSCRIBE request.
+// On error, none of the handlers remain registered.
+func (s *Stream) subscribe(subs []subscription) error {
+	_, err := s.subscribeTimed(subs)
+	return err
+}
+
+// subscribeTimed is subscribe, returning the confirmation latency.
+func (s *Stream) subscribeTimed(subs []subscription) (time.Duration, error) {
+	params := make([]interface{}, 0, len(subs))
+
+	for i, sub := range subs {
+		if _, loaded := s.handlers.LoadOrStore(sub.stream, sub.handler); loaded {
+			for _, prev := range subs[:i] {
+				s.handlers.Delete(prev.stream)
+			}
+			return 0, fmt.Errorf("%w: %s", ErrStreamSubscribed, sub.stream)
+		}
+
+		params = append(params, sub.stream)
+	}
+
+	start := s.clock.Now()
+
+	id, rc := s.enqueue(wsMethodRequest{
 		Method: MethodWsSubscribe,
-		Params: []interface{}{stream},
+		Params: params,
+	})
+	resp := <-rc
+	latency := s.clock.Now().Sub(start)
+
+	if err := checkConfirmation(MethodWsSubscribe, id, resp); err != nil {
+		for _, sub := range subs {
+			s.handlers.Delete(sub.stream)
+		}
+		return 0, err
+	}
+
+	return latency, nil
+}
+
+// Call sends an arbitrary method request and returns its result.
+// It is an escape hatch for methods without a typed wrapper.
+// Subscriptions made with Call do not register a handler,
+// use SubscribeRaw or Subscribe instead.
+func (s *Stream) Call(method string, params ...interface{}) (interface{}, error) {
+	resp := <-s.addQueue(wsMethodRequest{
+		Method: method,
+		Params: params,
 	})
 
 	if resp.Error != nil {
-		s.handlers.Delete(stream)
-		return fmt.Errorf("stream.Subscribe: %w", resp.Error)
+		return nil, fmt.Errorf("stream.Call %s: %w", method, resp.Error)
 	}
 
-	return nil
+	return resp.Result, nil
 }
 
 func (s *Stream) Unsubscribe(stream string) error {
@@ -329,7 +947,7 @@ func (s *Stream) Unsubscribe(stream string) error {
 	}
 
 	if handler, ok := s.handlers.LoadAndDelete(stream); ok {
-		handler.Done()
+		s.handlerDone(stream, handler)
 	}
 
 	return nil
@@ -19,14 +19,67 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package binance
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/muhlemmer/yatgo/internal/driver"
 	"github.com/rs/zerolog"
 )
 
+// newTestWsServer starts a websocket stub server,
+// which calls handler for each connection.
+// It returns the Hosts to connect a Stream to the server.
+// The server is closed when the test ends.
+func newTestWsServer(t *testing.T, handler func(conn *websocket.Conn)) Hosts {
+	var upgrader websocket.Upgrader
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		handler(conn)
+	}))
+	t.Cleanup(srv.Close)
+
+	return Hosts{
+		WsBase: "ws://" + srv.Listener.Addr().String(),
+	}
+}
+
+// echoMethods replies to each method request with a null result,
+// until the connection is closed.
+func echoMethods(conn *websocket.Conn) {
+	for {
+		var req wsMethodRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if err := conn.WriteJSON(streamMessage{ID: req.ID}); err != nil {
+			return
+		}
+	}
+}
+
 type testHandler struct {
 	ctx    context.Context
 	stream string
@@ -56,6 +109,63 @@ type panicHandler struct{}
 func (panicHandler) Event([]byte) { panic("foo") }
 func (panicHandler) Done()        {}
 
+type errPanicHandler struct{}
+
+func (errPanicHandler) Event([]byte) { panic(errors.New("foo")) }
+func (errPanicHandler) Done()        {}
+
+func TestStream_dispatch_panicPolicy(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	tests := []struct {
+		name       string
+		policy     PanicPolicy
+		handler    driver.JSONHandler
+		wantPanic  bool
+		wantNotify bool
+	}{
+		{"recover error", Recover, errPanicHandler{}, false, false},
+		{"recover value", Recover, panicHandler{}, true, false},
+		{"notify error", RecoverAndNotify, errPanicHandler{}, false, true},
+		{"notify value", RecoverAndNotify, panicHandler{}, false, true},
+		{"propagate error", Propagate, errPanicHandler{}, true, false},
+		{"propagate value", Propagate, panicHandler{}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notified []string
+
+			cfg := newStreamConfig([]StreamOption{
+				WithPanicPolicy(tt.policy),
+				WithPanicHook(func(stream string, value interface{}) {
+					notified = append(notified, stream)
+				}),
+			})
+			s := &Stream{
+				ctx:         logger.WithContext(testCTX),
+				panicPolicy: cfg.panicPolicy,
+				panicHook:   cfg.panicHook,
+			}
+			s.handlers.Store("handler", driver.ContextAdapter(tt.handler))
+
+			func() {
+				defer func() {
+					if panicked := recover() != nil; panicked != tt.wantPanic {
+						t.Errorf("Stream.dispatch() panicked = %v, want %v", panicked, tt.wantPanic)
+					}
+				}()
+
+				s.wg.Add(1)
+				s.dispatch([]byte(`{"stream":"handler","data":"Hello, World!"}`), time.Now())
+			}()
+
+			if got := len(notified) == 1 && notified[0] == "handler"; got != tt.wantNotify {
+				t.Errorf("Stream.dispatch() notified = %v, want %v", notified, tt.wantNotify)
+			}
+		})
+	}
+}
+
 func TestStream_dispatch(t *testing.T) {
 	logger := zerolog.New(zerolog.NewTestWriter(t))
 
@@ -100,22 +210,34 @@ func TestStream_dispatch(t *testing.T) {
 		},
 		{
 			"error",
-			`{"error":{"Code":3,"Msg":"foobar"},"id":1}`,
+			`{"error":{"code":3,"msg":"foobar"},"id":1}`,
 			wsMethodResponse{
 				ID: 1,
 				Error: &wsMethodError{
-					Code: 3,
-					Msg:  "foobar",
+					ErrorCode: 3,
+					ErrorMsg:  "foobar",
 				},
 			},
 			nil,
 		},
 		{
-			"recover",
+			"not combined",
+			`{"e":"aggTrade","s":"BTCUSDT"}`,
+			wsMethodResponse{},
+			nil,
+		},
+		{
+			"invalid json",
 			`!`,
 			wsMethodResponse{},
 			nil,
 		},
+		{
+			"truncated json",
+			`{"stream":"handler","data":["Hello,`,
+			wsMethodResponse{},
+			nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -124,14 +246,14 @@ func TestStream_dispatch(t *testing.T) {
 			}
 
 			rc := make(chan wsMethodResponse, 1)
-			s.addReponseChan(rc)
+			s.router.add(rc)
 
 			handler := newTestHandler(s.ctx, "dispatch_test", 1)
 
-			s.handlers.Store("handler", handler)
+			s.handlers.Store("handler", driver.ContextAdapter(handler))
 
 			s.wg.Add(1)
-			go s.dispatch([]byte(tt.data))
+			go s.dispatch([]byte(tt.data), time.Now())
 			s.wg.Wait()
 
 			close(rc)
@@ -149,62 +271,1111 @@ func TestStream_dispatch(t *testing.T) {
 		})
 	}
 
-	t.Run("panic", func(t *testing.T) {
-		s := &Stream{
-			ctx: logger.WithContext(testCTX),
+	t.Run("panic", func(t *testing.T) {
+		s := &Stream{
+			ctx: logger.WithContext(testCTX),
+		}
+
+		s.handlers.Store("handler", driver.ContextAdapter(panicHandler{}))
+
+		defer func() {
+			if recover() == nil {
+				t.Error("recover returned nil")
+			}
+		}()
+
+		s.wg.Add(1)
+		s.dispatch([]byte(`{"stream":"handler","data":["Hello, World!"]}`), time.Now())
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		s := &Stream{
+			ctx: logger.WithContext(testCTX),
+		}
+
+		handler := newTestHandler(s.ctx, "dispatch_test", 2)
+		s.handlers.Store("handler", rawHandler{
+			stream: "handler",
+			h:      jsonEventHandler{driver.DropStale(handler, time.Second)},
+			refs:   new(int32),
+		})
+
+		s.wg.Add(2)
+		s.dispatch([]byte(`{"stream":"handler","data":"stale"}`), time.Now().Add(-time.Minute))
+		s.dispatch([]byte(`{"stream":"handler","data":"fresh"}`), time.Now())
+		close(handler.events)
+
+		var got []string
+		for data := range handler.events {
+			got = append(got, string(data))
+		}
+		if want := []string{`"fresh"`}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Stream.dispatch() events = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestStream_DecodeFailures(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	s := &Stream{
+		ctx: logger.WithContext(testCTX),
+	}
+
+	h := newTestKlineHandler(1)
+	s.handlers.Store("btcusdt@kline_1m", driver.ContextAdapter(&klineHandler{h}))
+	s.handlers.Store("error", driver.ContextAdapter(errPanicHandler{}))
+
+	s.wg.Add(4)
+	s.dispatch([]byte(`{"stream":"btcusdt@kline_1m","data":{"k":"foo"}}`), time.Now())
+	s.dispatch([]byte(`{"stream":"btcusdt@kline_1m","data":!}`), time.Now())
+	s.dispatch([]byte(`{"stream":"error","data":{}}`), time.Now())
+	s.dispatch([]byte(`{"stream":"btcusdt@kline_1m","data":{"s":"BTCUSDT"}}`), time.Now())
+
+	if got := <-h.got; got.Symbol != "BTCUSDT" {
+		t.Errorf("Stream.dispatch() event after decode failure = %v", got)
+	}
+
+	want := map[string]int64{"btcusdt@kline_1m": 1}
+	if got := s.DecodeFailures(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stream.DecodeFailures() = %v, want %v", got, want)
+	}
+}
+
+// stashHandler retains event data without copying it.
+type stashHandler struct {
+	mtx  sync.Mutex
+	data [][]byte
+}
+
+func (h *stashHandler) Event(data []byte) {
+	h.mtx.Lock()
+	h.data = append(h.data, data)
+	h.mtx.Unlock()
+}
+
+func (h *stashHandler) Done() {}
+
+func TestStream_dispatchBuffer(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t)).Level(zerolog.WarnLevel)
+
+	s := &Stream{
+		ctx: logger.WithContext(testCTX),
+	}
+
+	h := new(stashHandler)
+	s.handlers.Store("stash", driver.ContextAdapter(h))
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		buf := readBuffers.Get().(*bytes.Buffer)
+		buf.Reset()
+		fmt.Fprintf(buf, `{"stream":"stash","data":%d}`, i)
+
+		s.wg.Add(1)
+		s.dispatchBuffer(buf, time.Now())
+	}
+
+	// Overwrite any buffer that went back to the pool.
+	for i := 0; i < n; i++ {
+		buf := readBuffers.Get().(*bytes.Buffer)
+		buf.Reset()
+		buf.WriteString(`{"stream":"stash","data":"overwritten"}`)
+		putReadBuffer(buf)
+	}
+
+	if len(h.data) != n {
+		t.Fatalf("handler received %d events, want %d", len(h.data), n)
+	}
+	for i, data := range h.data {
+		if want := strconv.Itoa(i); string(data) != want {
+			t.Errorf("retained event %d = %s, want %s", i, data, want)
+		}
+	}
+}
+
+func TestStream_dispatch_notCombined(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.WarnLevel)
+
+	s := &Stream{
+		ctx: logger.WithContext(testCTX),
+	}
+
+	handler := newTestHandler(s.ctx, "btcusdt@aggTrade", 1)
+	s.handlers.Store("btcusdt@aggTrade", driver.ContextAdapter(handler))
+
+	s.wg.Add(1)
+	s.dispatch([]byte(`{"e":"aggTrade","E":1,"s":"BTCUSDT","p":"1.0"}`), time.Now())
+
+	select {
+	case data := <-handler.events:
+		t.Errorf("Stream.dispatch() unexpected event %s", data)
+	default:
+	}
+
+	var entry struct {
+		Level string `json:"level"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Level != "error" || entry.Error != ErrNotCombined.Error() {
+		t.Errorf("Stream.dispatch() logged %s, want error %q", buf.Bytes(), ErrNotCombined)
+	}
+}
+
+func Test_isControlMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"empty", "", true},
+		{"response", `{"result":null,"id":1}`, true},
+		{"error", `{"error":{"code":2,"msg":"Invalid request"},"id":1}`, true},
+		{"stream event", `{"stream":"btcusdt@trade","data":{}}`, false},
+		{"large response", `{"result":["` + strings.Repeat("a", maxControlMessage) + `"],"id":1}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isControlMessage([]byte(tt.data)); got != tt.want {
+				t.Errorf("isControlMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkStream_dispatch(b *testing.B) {
+	data := []byte(`{"result":null,"id":1}`)
+
+	b.Run("inline", func(b *testing.B) {
+		s := &Stream{ctx: context.Background()}
+		for i := 0; i < b.N; i++ {
+			s.wg.Add(1)
+			s.dispatch(data, time.Now())
+		}
+		s.wg.Wait()
+	})
+
+	b.Run("goroutine", func(b *testing.B) {
+		s := &Stream{ctx: context.Background()}
+		for i := 0; i < b.N; i++ {
+			s.wg.Add(1)
+			go s.dispatch(data, time.Now())
+		}
+		s.wg.Wait()
+	})
+}
+
+func Test_parseStreamMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    streamMessage
+		wantErr error
+	}{
+		{
+			"stream event",
+			`{"stream":"handler","data":["Hello, World!"]}`,
+			streamMessage{
+				Stream: "handler",
+				Data:   []byte(`["Hello, World!"]`),
+			},
+			nil,
+		},
+		{
+			"truncated",
+			`{"stream":"handler","data":["Hello,`,
+			streamMessage{},
+			ErrInvalidMessage,
+		},
+		{
+			"not an object",
+			`["Hello, World!"]`,
+			streamMessage{},
+			ErrInvalidMessage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStreamMessage([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseStreamMessage() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStreamMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStream(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr bool
+	}{
+		{
+			"context error",
+			logger.WithContext(errCTX),
+			true,
+		},
+		{
+			"success",
+			logger.WithContext(testCTX),
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(tt.ctx)
+			defer cancel()
+
+			stream, err := NewStream(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewStream() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr {
+				cancel()
+				stream.wg.Wait()
+			}
+		})
+	}
+}
+
+func TestNewStream_WithHosts(t *testing.T) {
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	hosts := newTestWsServer(t, echoMethods)
+
+	s, err := NewStream(logger.WithContext(ctx), WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-s.addQueue(wsMethodRequest{Method: MethodWsListSubscriptions}); got.Error != nil || got.ID != 1 {
+		t.Errorf("Stream method response = %v", got)
+	}
+
+	cancel()
+	s.wg.Wait()
+}
+
+func TestNewStream_WithSubscription(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	const kline = `{"stream":"btcusdt@kline_1m","data":{"e":"kline","s":"BTCUSDT","k":{"t":1,"i":"1m","c":"1.0"}}}`
+
+	tests := []struct {
+		name    string
+		reply   func(conn *websocket.Conn, req wsMethodRequest)
+		want    []interface{}
+		wantErr bool
+	}{
+		{
+			"success",
+			func(conn *websocket.Conn, req wsMethodRequest) {
+				conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"result":null,"id":%d}`, req.ID)))
+				conn.WriteMessage(websocket.TextMessage, []byte(kline))
+			},
+			[]interface{}{"btcusdt@kline_1m", "ethusdt@kline_1m"},
+			false,
+		},
+		{
+			"error",
+			func(conn *websocket.Conn, req wsMethodRequest) {
+				conn.WriteJSON(streamMessage{ID: req.ID, Error: &wsMethodError{ErrorCode: 2, ErrorMsg: "Invalid request"}})
+			},
+			nil,
+			true,
+		},
+		{
+			"unexpected result",
+			func(conn *websocket.Conn, req wsMethodRequest) {
+				conn.WriteJSON(streamMessage{ID: req.ID, Result: []string{"btcusdt@kline_1m"}})
+			},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(testCTX)
+			defer cancel()
+
+			reqs := make(chan wsMethodRequest, 1)
+
+			hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+				var req wsMethodRequest
+				if err := conn.ReadJSON(&req); err != nil {
+					return
+				}
+				reqs <- req
+				tt.reply(conn, req)
+
+				for {
+					if _, _, err := conn.ReadMessage(); err != nil {
+						return
+					}
+				}
+			})
+
+			btc := newTestKlineHandler(10)
+			eth := newTestKlineHandler(10)
+
+			s, err := NewStream(logger.WithContext(ctx), WithHosts(hosts),
+				WithKlines("btcusdt", Minute, btc),
+				WithKlines("ethusdt", Minute, eth),
+			)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			req := <-reqs
+			if req.Method != MethodWsSubscribe {
+				t.Errorf("NewStream() method = %s, want %s", req.Method, MethodWsSubscribe)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(req.Params, tt.want) {
+				t.Errorf("NewStream() params = %v, want %v", req.Params, tt.want)
+			}
+
+			if event := <-btc.got; event.Kline.Close != "1.0" {
+				t.Errorf("NewStream() kline event = %v", event)
+			}
+
+			cancel()
+			s.wg.Wait()
+		})
+	}
+}
+
+type testContextHandler struct {
+	events chan context.Context
+	done   chan context.Context
+}
+
+func (h *testContextHandler) Event(ctx context.Context, _ []byte) { h.events <- ctx }
+func (h *testContextHandler) Done(ctx context.Context)            { h.done <- ctx }
+
+func TestStream_SubscribeContext(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		var req wsMethodRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		conn.WriteJSON(streamMessage{ID: req.ID})
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"stream":"btcusdt@aggTrade","data":{}}`))
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	handler := &testContextHandler{
+		events: make(chan context.Context, 1),
+		done:   make(chan context.Context, 1),
+	}
+
+	s, err := NewStream(logger.WithContext(ctx), WithHosts(hosts),
+		WithContextSubscription("btcusdt@aggTrade", handler),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventCTX := <-handler.events
+	if err := eventCTX.Err(); err != nil {
+		t.Fatalf("Event() context error = %v, want nil", err)
+	}
+
+	cancel()
+	s.wg.Wait()
+
+	select {
+	case <-eventCTX.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Event() context not canceled after stream close")
+	}
+
+	if err := (<-handler.done).Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Done() context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStream_writeTimeout(t *testing.T) {
+	stop := make(chan struct{})
+
+	hosts := newTestWsServer(t, func(*websocket.Conn) {
+		// never read, so the send buffers fill up
+		<-stop
+	})
+	defer close(stop)
+
+	s, err := NewStream(testCTX, WithHosts(hosts), WithWriteTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// larger than any socket buffer
+	param := strings.Repeat("x", 32<<20)
+
+	select {
+	case resp := <-s.addQueue(wsMethodRequest{Method: MethodWsSubscribe, Params: []interface{}{param}}):
+		var netErr net.Error
+		if !errors.As(resp.Error, &netErr) || !netErr.Timeout() {
+			t.Errorf("Stream.addQueue() error = %v, want timeout", resp.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stream write did not time out")
+	}
+
+	s.wg.Wait()
+	if s.ctx.Err() == nil {
+		t.Error("Stream not closed after write timeout")
+	}
+}
+
+func TestStream_readTimeout(t *testing.T) {
+	stop := make(chan struct{})
+
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		// a single ping keeps the connection alive for another period
+		time.Sleep(50 * time.Millisecond)
+		conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+		<-stop
+	})
+	defer close(stop)
+
+	start := time.Now()
+
+	s, err := NewStream(testCTX, WithHosts(hosts), WithReadTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-s.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stream read did not time out")
+	}
+	s.wg.Wait()
+
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("Stream closed after %s, ping did not extend the read deadline", d)
+	}
+}
+
+type testRawHandler struct {
+	events chan string
+	done   chan struct{}
+}
+
+func (h *testRawHandler) Event(stream string, _ []byte) { h.events <- stream }
+func (h *testRawHandler) Done()                         { close(h.done) }
+
+func TestStream_SubscribeRaw(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	streams := []string{"btcusdt@kline_1m", "btcusdt@aggTrade", "btcusdt@bookTicker"}
+
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		var req wsMethodRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		conn.WriteJSON(streamMessage{ID: req.ID})
+
+		for _, stream := range streams {
+			conn.WriteJSON(streamMessage{Stream: stream, Data: []byte(`{}`)})
+		}
+
+		echoMethods(conn)
+	})
+
+	s, err := NewStream(logger.WithContext(ctx), WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := &testRawHandler{
+		events: make(chan string, len(streams)),
+		done:   make(chan struct{}),
+	}
+
+	if err = s.SubscribeRaw(nil, handler); !errors.Is(err, ErrNoStreams) {
+		t.Errorf("Stream.SubscribeRaw() error = %v, want %v", err, ErrNoStreams)
+	}
+	if err = s.SubscribeRaw(streams, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]bool)
+	for range streams {
+		got[<-handler.events] = true
+	}
+	for _, stream := range streams {
+		if !got[stream] {
+			t.Errorf("Stream.SubscribeRaw() no event for %s", stream)
+		}
+	}
+
+	if err = s.Unsubscribe(streams[0]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-handler.done:
+		t.Fatal("Done called with streams remaining")
+	default:
+	}
+
+	cancel()
+	s.wg.Wait()
+	<-handler.done
+}
+
+func TestStream_Subscriptions(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	hosts := newTestWsServer(t, echoMethods)
+
+	s, err := NewStream(logger.WithContext(ctx), WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.Subscriptions(); len(got) != 0 {
+		t.Errorf("Stream.Subscriptions() = %v, want empty", got)
+	}
+
+	trades := newTestHandler(ctx, "btcusdt@aggTrade", 1)
+	raw := &testRawHandler{done: make(chan struct{})}
+	ctxHandler := &testContextHandler{done: make(chan context.Context, 1)}
+
+	if err = s.Subscribe("btcusdt@aggTrade", trades); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SubscribeRaw([]string{"ethusdt@bookTicker"}, raw); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SubscribeContext("bnbusdt@trade", ctxHandler); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"bnbusdt@trade", "btcusdt@aggTrade", "ethusdt@bookTicker"}
+	if got := s.Subscriptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stream.Subscriptions() = %v, want %v", got, want)
+	}
+
+	handlers := map[string]interface{}{
+		"btcusdt@aggTrade":   trades,
+		"ethusdt@bookTicker": raw,
+		"bnbusdt@trade":      ctxHandler,
+	}
+	for stream, want := range handlers {
+		if got, ok := s.HandlerFor(stream); !ok || got != want {
+			t.Errorf("Stream.HandlerFor(%s) = %T, %v, want %T", stream, got, ok, want)
+		}
+	}
+	if _, ok := s.HandlerFor("xrpusdt@trade"); ok {
+		t.Error("Stream.HandlerFor() ok for unsubscribed stream")
+	}
+
+	if err = s.Unsubscribe("btcusdt@aggTrade"); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"bnbusdt@trade", "ethusdt@bookTicker"}
+	if got := s.Subscriptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stream.Subscriptions() = %v after Unsubscribe, want %v", got, want)
+	}
+
+	cancel()
+	s.wg.Wait()
+}
+
+func TestNewStream_WithSubscriptionsInURL(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	const kline = `{"stream":"btcusdt@kline_1m","data":{"e":"kline","s":"BTCUSDT","k":{"t":1,"i":"1m","c":"1.0"}}}`
+
+	var upgrader websocket.Upgrader
+	methods := make(chan wsMethodRequest, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("streams"), "btcusdt@kline_1m/ethusdt@kline_1m"; got != want {
+			t.Errorf("streams query = %q, want %q", got, want)
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteMessage(websocket.TextMessage, []byte(kline))
+
+		for {
+			var req wsMethodRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			methods <- req
+		}
+	}))
+	defer srv.Close()
+
+	btc := newTestKlineHandler(10)
+
+	s, err := NewStream(logger.WithContext(ctx),
+		WithHosts(Hosts{WsBase: "ws://" + srv.Listener.Addr().String()}),
+		WithSubscriptionsInURL(),
+		WithKlines("btcusdt", Minute, btc),
+		WithKlines("ethusdt", Minute, newTestKlineHandler(10)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event := <-btc.got; event.Kline.Close != "1.0" {
+		t.Errorf("NewStream() kline event = %v", event)
+	}
+
+	select {
+	case req := <-methods:
+		t.Errorf("NewStream() sent %s request", req.Method)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	s.wg.Wait()
+}
+
+func TestNewStream_WithSubscriptionsInURL_duplicate(t *testing.T) {
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	_, err := NewStream(testCTX,
+		WithHosts(hosts),
+		WithSubscriptionsInURL(),
+		WithKlines("btcusdt", Minute, newTestKlineHandler(1)),
+		WithKlines("btcusdt", Minute, newTestKlineHandler(1)),
+	)
+	if !errors.Is(err, ErrStreamSubscribed) {
+		t.Errorf("NewStream() error = %v, want %v", err, ErrStreamSubscribed)
+	}
+}
+
+func TestNewStream_WithHeader(t *testing.T) {
+	got := make(chan http.Header, 1)
+
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Clone()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		echoMethods(conn)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	s, err := NewStream(ctx,
+		WithHosts(Hosts{WsBase: "ws://" + srv.Listener.Addr().String()}),
+		WithHeader(http.Header{"X-Mbx-Apikey": {"key"}}),
+		WithHeader(http.Header{"User-Agent": {"yatgo"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := <-got
+	if v := header.Get("X-Mbx-Apikey"); v != "key" {
+		t.Errorf("NewStream() handshake X-Mbx-Apikey = %q, want %q", v, "key")
+	}
+	if v := header.Get("User-Agent"); v != "yatgo" {
+		t.Errorf("NewStream() handshake User-Agent = %q, want %q", v, "yatgo")
+	}
+
+	cancel()
+	s.wg.Wait()
+}
+
+type donePanicHandler struct{}
+
+func (donePanicHandler) Event([]byte) {}
+func (donePanicHandler) Done()        { panic("done") }
+
+func TestStream_CloseErr(t *testing.T) {
+	hosts := newTestWsServer(t, echoMethods)
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	good := newTestHandler(ctx, "good", 1)
+
+	s, err := NewStream(ctx,
+		WithHosts(hosts),
+		WithSubscriptionsInURL(),
+		WithSubscription("bad", donePanicHandler{}),
+		WithSubscription("good", good),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.CloseErr(); err != nil {
+		t.Errorf("Stream.CloseErr() = %v while open", err)
+	}
+
+	cancel()
+	s.wg.Wait()
+
+	if _, ok := <-good.events; ok {
+		t.Error("Stream.close() did not call Done of the good handler")
+	}
+
+	var ce *CloseError
+	if err = s.CloseErr(); !errors.As(err, &ce) {
+		t.Fatalf("Stream.CloseErr() = %v, want %T", err, ce)
+	}
+	if want := map[string]interface{}{"bad": "done"}; !reflect.DeepEqual(ce.Panics, want) {
+		t.Errorf("CloseError.Panics = %v, want %v", ce.Panics, want)
+	}
+}
+
+func TestStream_close_donePanic(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	hosts := newTestWsServer(t, echoMethods)
+
+	ctx, cancel := context.WithCancel(logger.WithContext(testCTX))
+	defer cancel()
+
+	opts := []StreamOption{WithHosts(hosts), WithSubscriptionsInURL()}
+
+	var handlers []*testHandler
+	for i := 0; i < 5; i++ {
+		stream := fmt.Sprintf("good%d", i)
+		h := newTestHandler(ctx, stream, 1)
+		handlers = append(handlers, h)
+		opts = append(opts, WithSubscription(stream, h))
+
+		if i == 2 {
+			opts = append(opts,
+				WithSubscription("bad", donePanicHandler{}),
+				WithSubscription("unsubscribed", donePanicHandler{}),
+			)
+		}
+	}
+
+	s, err := NewStream(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Done panics on unsubscribe are recovered as well.
+	if err = s.Unsubscribe("unsubscribed"); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	s.wg.Wait()
+
+	for _, h := range handlers {
+		if _, ok := <-h.events; ok {
+			t.Errorf("Stream.close() did not call Done of %s", h.stream)
 		}
+	}
+}
 
-		s.handlers.Store("handler", panicHandler{})
+func TestNewStream_WithDialer(t *testing.T) {
+	var upgrader websocket.Upgrader
 
-		defer func() {
-			if recover() == nil {
-				t.Error("recover returned nil")
-			}
-		}()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
 
-		s.wg.Add(1)
-		s.dispatch([]byte(`{"stream":"handler","data":["Hello, World!"]}`))
-	})
-}
+		echoMethods(conn)
+	}))
+	defer srv.Close()
 
-func TestNewStream(t *testing.T) {
-	logger := zerolog.New(zerolog.NewTestWriter(t))
+	hosts := Hosts{
+		WsBase: "wss://" + srv.Listener.Addr().String(),
+	}
+	pinned := srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
 
 	tests := []struct {
 		name    string
-		ctx     context.Context
+		pool    *x509.CertPool
 		wantErr bool
 	}{
-		{
-			"context error",
-			logger.WithContext(errCTX),
-			true,
-		},
-		{
-			"success",
-			logger.WithContext(testCTX),
-			false,
-		},
+		{"pinned", pinned, false},
+		{"wrong pool", x509.NewCertPool(), true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(tt.ctx)
+			ctx, cancel := context.WithCancel(testCTX)
 			defer cancel()
 
-			stream, err := NewStream(ctx)
+			dialer := &websocket.Dialer{
+				TLSClientConfig: &tls.Config{RootCAs: tt.pool},
+			}
+
+			s, err := NewStream(ctx, WithHosts(hosts), WithDialer(dialer))
 			if (err != nil) != tt.wantErr {
-				t.Errorf("NewStream() error = %v, wantErr %v", err, tt.wantErr)
+				t.Fatalf("NewStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var certErr x509.UnknownAuthorityError
+				if !errors.As(err, &certErr) {
+					t.Errorf("NewStream() error = %v, want %T", err, certErr)
+				}
 				return
 			}
 
-			if !tt.wantErr {
-				cancel()
-				stream.wg.Wait()
-			}
+			cancel()
+			s.wg.Wait()
 		})
 	}
 }
 
+func TestNewStream_WithQueueSize(t *testing.T) {
+	hosts := newTestWsServer(t, echoMethods)
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	s, err := NewStream(ctx, WithHosts(hosts), WithQueueSize(2*DefaultQueueSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cap(s.queue); got != 2*DefaultQueueSize {
+		t.Errorf("NewStream() queue capacity = %d, want %d", got, 2*DefaultQueueSize)
+	}
+
+	// The rate limiter drains 5 per second, so this would block on a default queue.
+	start := time.Now()
+	for i := 0; i < DefaultQueueSize+30; i++ {
+		s.addQueue(wsMethodRequest{Method: MethodWsListSubscriptions})
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Stream.addQueue() blocked for %s", d)
+	}
+
+	cancel()
+	s.wg.Wait()
+}
+
+func TestWithQueueSize(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{-1, DefaultQueueSize},
+		{0, 0},
+		{10, 10},
+	}
+	for _, tt := range tests {
+		cfg := newStreamConfig([]StreamOption{WithQueueSize(tt.n)})
+		if cfg.queueSize != tt.want {
+			t.Errorf("WithQueueSize(%d) = %d, want %d", tt.n, cfg.queueSize, tt.want)
+		}
+	}
+}
+
+func TestNewStream_noLimit(t *testing.T) {
+	hosts := newTestWsServer(t, echoMethods)
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	s, err := NewStream(ctx, WithHosts(hosts), withLimiters(noLimit{}, noLimit{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default limiter sends 5 per second, so this would take about 4 seconds.
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		if _, err := s.Call(MethodWsListSubscriptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Stream.Call() throttled for %s", d)
+	}
+
+	cancel()
+	s.wg.Wait()
+}
+
+func TestStream_CloseErr_frame(t *testing.T) {
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "Too many streams")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		conn.ReadMessage() // wait for the close reply
+	})
+
+	s, err := NewStream(testCTX, WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.wg.Wait()
+
+	var frame *websocket.CloseError
+	if err = s.CloseErr(); !errors.As(err, &frame) {
+		t.Fatalf("Stream.CloseErr() = %v, want %T", err, frame)
+	}
+	if frame.Code != websocket.ClosePolicyViolation || frame.Text != "Too many streams" {
+		t.Errorf("Stream.CloseErr() frame = %d %q", frame.Code, frame.Text)
+	}
+}
+
+func Test_jitter(t *testing.T) {
+	if got := jitter(0); got != 0 {
+		t.Errorf("jitter(0) = %s, want 0", got)
+	}
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		got := jitter(time.Second)
+		if got < 0 || got >= time.Second {
+			t.Fatalf("jitter() = %s, out of range", got)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 50 {
+		t.Errorf("jitter() returned %d distinct values out of 100", len(seen))
+	}
+}
+
+func TestNewStream_WithOpenJitter(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		opens []time.Time
+	)
+
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		mtx.Lock()
+		opens = append(opens, time.Now())
+		mtx.Unlock()
+
+		echoMethods(conn)
+	})
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	streams := make([]*Stream, 0, 5)
+	for i := 0; i < 5; i++ {
+		s, err := NewStream(ctx, WithHosts(hosts), WithOpenJitter(50*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, s)
+	}
+
+	cancel()
+	for _, s := range streams {
+		s.wg.Wait()
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	minGap, maxGap := time.Duration(math.MaxInt64), time.Duration(0)
+	for i := 1; i < len(opens); i++ {
+		gap := opens[i].Sub(opens[i-1])
+		if gap < minGap {
+			minGap = gap
+		}
+		if gap > maxGap {
+			maxGap = gap
+		}
+	}
+
+	if maxGap-minGap < time.Millisecond {
+		t.Errorf("NewStream() opens are periodic, gaps between %s and %s", minGap, maxGap)
+	}
+
+	ctx, cancel = context.WithCancel(testCTX)
+	cancel()
+	if _, err := NewStream(ctx, WithHosts(hosts), WithOpenJitter(time.Hour)); !errors.Is(err, context.Canceled) {
+		t.Errorf("NewStream() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStream_closeRace(t *testing.T) {
+	hosts := newTestWsServer(t, echoMethods)
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(testCTX)
+
+		s, err := NewStream(ctx, WithHosts(hosts))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		for j := 0; j < 10; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := 0; k < 20; k++ {
+					resp := <-s.addQueue(wsMethodRequest{Method: MethodWsListSubscriptions})
+					if resp.Error != nil && !errors.Is(resp.Error, ErrStreamClosed) {
+						t.Errorf("Stream.addQueue() error = %v, want nil or %v", resp.Error, ErrStreamClosed)
+					}
+				}
+			}()
+		}
+
+		cancel()
+		wg.Wait()
+		s.wg.Wait()
+	}
+}
+
 func TestStream_queue(t *testing.T) {
 	ctx, cancel := context.WithCancel(testCTX)
 	defer cancel()
@@ -238,8 +1409,54 @@ func TestStream_queue(t *testing.T) {
 		Params: []interface{}{"combined"},
 	})
 
-	if got := <-rc; got.Error == nil {
-		t.Error("Stream method request expected error")
+	if got := <-rc; !errors.Is(got.Error, ErrStreamClosed) {
+		t.Errorf("Stream method request error = %v, want %v", got.Error, ErrStreamClosed)
+	}
+}
+
+func TestStream_closed(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	received := make(chan struct{})
+
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		// never respond
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			received <- struct{}{}
+		}
+	})
+
+	s, err := NewStream(logger.WithContext(ctx), WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending := s.addQueue(wsMethodRequest{Method: MethodWsListSubscriptions})
+	<-received
+
+	cancel()
+	s.wg.Wait()
+
+	if got := <-pending; !errors.Is(got.Error, ErrStreamClosed) {
+		t.Errorf("pending request error = %v, want %v", got.Error, ErrStreamClosed)
+	}
+	if got := <-s.addQueue(wsMethodRequest{Method: MethodWsListSubscriptions}); !errors.Is(got.Error, ErrStreamClosed) {
+		t.Errorf("Stream.addQueue() error = %v, want %v", got.Error, ErrStreamClosed)
+	}
+	if err := s.Subscribe("btcusdt@aggTrade", newTestHandler(ctx, "btcusdt@aggTrade", 1)); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("Stream.Subscribe() error = %v, want %v", err, ErrStreamClosed)
+	}
+	if err := s.Unsubscribe("btcusdt@aggTrade"); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("Stream.Unsubscribe() error = %v, want %v", err, ErrStreamClosed)
+	}
+	if err := s.Unsubscribe("btcusdt@aggTrade"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream.Unsubscribe() error = %v, want cause %v", err, context.Canceled)
 	}
 }
 
@@ -332,8 +1549,8 @@ func TestMethodRequest(t *testing.T) {
 			wsMethodResponse{
 				ID: 6,
 				Error: wsMethodError{
-					Code: 0,
-					Msg:  "Unknown property",
+					ErrorCode: 0,
+					ErrorMsg:  "Unknown property",
 				},
 			},
 			true,
@@ -418,6 +1635,107 @@ func TestStream_Subscribe(t *testing.T) {
 	s.wg.Wait()
 }
 
+func TestStream_SubscribeTimed(t *testing.T) {
+	h := newTestHandler(testCTX, "btcusdt@aggTrade", 100)
+
+	latency, err := testStream.SubscribeTimed("btcusdt@aggTrade", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latency <= 0 {
+		t.Errorf("Stream.SubscribeTimed() latency = %s, want positive", latency)
+	}
+
+	if err = testStream.Unsubscribe("btcusdt@aggTrade"); err != nil {
+		t.Fatal(err)
+	}
+	for range h.events {
+	}
+}
+
+func TestStream_SubscribeTimed_delay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		var req wsMethodRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		time.Sleep(delay)
+		conn.WriteJSON(streamMessage{ID: req.ID})
+
+		echoMethods(conn)
+	})
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	s, err := NewStream(ctx, WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newTestHandler(ctx, "btcusdt@aggTrade", 1)
+	latency, err := s.SubscribeTimed("btcusdt@aggTrade", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latency < delay {
+		t.Errorf("Stream.SubscribeTimed() latency = %s, want at least %s", latency, delay)
+	}
+
+	if _, err = s.SubscribeTimed("btcusdt@aggTrade", h); !errors.Is(err, ErrStreamSubscribed) {
+		t.Errorf("Stream.SubscribeTimed() error = %v, want %v", err, ErrStreamSubscribed)
+	}
+
+	cancel()
+	s.wg.Wait()
+}
+
+func Test_checkConfirmation(t *testing.T) {
+	errFoo := errors.New("foo")
+
+	tests := []struct {
+		name    string
+		reqID   uint
+		resp    wsMethodResponse
+		wantErr error
+	}{
+		{
+			"null result",
+			1,
+			wsMethodResponse{ID: 1},
+			nil,
+		},
+		{
+			"error",
+			1,
+			wsMethodResponse{ID: 1, Error: errFoo},
+			errFoo,
+		},
+		{
+			"wrong id",
+			1,
+			wsMethodResponse{ID: 2},
+			&UnexpectedResponseError{Method: MethodWsSubscribe, RequestID: 1, ResponseID: 2},
+		},
+		{
+			"non-null result",
+			1,
+			wsMethodResponse{ID: 1, Result: []interface{}{"btcusdt@aggTrade"}},
+			&UnexpectedResponseError{Method: MethodWsSubscribe, RequestID: 1, ResponseID: 1, Result: []interface{}{"btcusdt@aggTrade"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConfirmation(MethodWsSubscribe, tt.reqID, tt.resp)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("checkConfirmation() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestStream_Unsubscribe(t *testing.T) {
 	ctx, cancel := context.WithCancel(testCTX)
 	defer cancel()
@@ -447,3 +1765,69 @@ func TestStream_Unsubscribe(t *testing.T) {
 	s.cancel()
 	s.wg.Wait()
 }
+
+func TestStream_ConnInfo(t *testing.T) {
+	info := testStream.ConnInfo()
+
+	if info.RemoteAddr == "" {
+		t.Errorf("Stream.ConnInfo() RemoteAddr empty: %v", info)
+	}
+	if info.LocalAddr == "" {
+		t.Errorf("Stream.ConnInfo() LocalAddr empty: %v", info)
+	}
+}
+
+func TestStream_Call(t *testing.T) {
+	hosts := newTestWsServer(t, func(conn *websocket.Conn) {
+		for {
+			var req wsMethodRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+
+			resp := streamMessage{ID: req.ID}
+			switch {
+			case req.Method == MethodWsGetProperty && reflect.DeepEqual(req.Params, []interface{}{"combined"}):
+				resp.Result = true
+			default:
+				resp.Error = &wsMethodError{ErrorCode: 2, ErrorMsg: "Invalid request"}
+			}
+			if err := conn.WriteJSON(resp); err != nil {
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(testCTX)
+	defer cancel()
+
+	s, err := NewStream(ctx, WithHosts(hosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		params  []interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"get property", MethodWsGetProperty, []interface{}{"combined"}, true, false},
+		{"error", "FOO", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Call(tt.method, tt.params...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Stream.Call() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Stream.Call() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	cancel()
+	s.wg.Wait()
+}
@@ -0,0 +1,171 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TickerEvent is the rolling 24 hour statistics of a symbol.
+type TickerEvent struct {
+	Event              string `json:"e"` // Event type ("24hrTicker")
+	Time               int64  `json:"E"` // Event time
+	Symbol             string `json:"s"`
+	PriceChange        string `json:"p"`
+	PriceChangePercent string `json:"P"`
+	WeightedAvgPrice   string `json:"w"`
+	FirstTradePrice    string `json:"x"` // Last price before the 24h window
+	LastPrice          string `json:"c"`
+	LastQuantity       string `json:"Q"`
+	BidPrice           string `json:"b"`
+	BidQuantity        string `json:"B"`
+	AskPrice           string `json:"a"`
+	AskQuantity        string `json:"A"`
+	Open               string `json:"o"`
+	High               string `json:"h"`
+	Low                string `json:"l"`
+	BaseVolume         string `json:"v"`
+	QuoteVolume        string `json:"q"`
+	OpenTime           int64  `json:"O"`
+	CloseTime          int64  `json:"C"`
+	FirstTradeID       int64  `json:"F"`
+	LastTradeID        int64  `json:"L"`
+	Trades             int64  `json:"n"`
+}
+
+type TickerHandler interface {
+	Event(TickerEvent)
+	Done()
+}
+
+// tickerArrayHandler decodes array streams,
+// which push the events of all symbols in one message.
+type tickerArrayHandler struct {
+	h TickerHandler
+}
+
+func (t *tickerArrayHandler) Event(data []byte) {
+	var events []TickerEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		panic(decodeError{fmt.Errorf("TickerHandler: %w", err)})
+	}
+
+	for _, event := range events {
+		t.h.Event(event)
+	}
+}
+
+func (t *tickerArrayHandler) Done() { t.h.Done() }
+
+const allTickersStream = "!ticker@arr"
+
+// SubscribeAllTickers subscribes handler to the 24 hour ticker of all symbols.
+// Every second, the tickers which changed are pushed as a single message,
+// of which each element is passed to handler.
+// This counts as one subscription, but is a high-volume stream.
+func (s *Stream) SubscribeAllTickers(handler TickerHandler) error {
+	return s.SubscribeRaw([]string{allTickersStream}, jsonEventHandler{&tickerArrayHandler{handler}})
+}
+
+func (s *Stream) UnsubscribeAllTickers() error {
+	return s.Unsubscribe(allTickersStream)
+}
+
+// Ticker windows of the rolling window statistics streams.
+const (
+	TickerWindow1h = "1h"
+	TickerWindow4h = "4h"
+	TickerWindow1d = "1d"
+)
+
+// ErrInvalidWindow is returned for an unsupported rolling ticker window.
+var ErrInvalidWindow = errors.New("binance: invalid ticker window")
+
+// WindowTickerEvent is the rolling window statistics of a symbol.
+type WindowTickerEvent struct {
+	Event              string `json:"e"` // Event type ("1hTicker", "4hTicker" or "1dTicker")
+	Time               int64  `json:"E"` // Event time
+	Symbol             string `json:"s"`
+	PriceChange        string `json:"p"`
+	PriceChangePercent string `json:"P"`
+	Open               string `json:"o"`
+	High               string `json:"h"`
+	Low                string `json:"l"`
+	LastPrice          string `json:"c"`
+	WeightedAvgPrice   string `json:"w"`
+	BaseVolume         string `json:"v"`
+	QuoteVolume        string `json:"q"`
+	OpenTime           int64  `json:"O"`
+	CloseTime          int64  `json:"C"`
+	FirstTradeID       int64  `json:"F"`
+	LastTradeID        int64  `json:"L"`
+	Trades             int64  `json:"n"`
+}
+
+type WindowTickerHandler interface {
+	Event(WindowTickerEvent)
+	Done()
+}
+
+type windowTickerHandler struct {
+	h WindowTickerHandler
+}
+
+func (t *windowTickerHandler) Event(data []byte) {
+	var event WindowTickerEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		panic(decodeError{fmt.Errorf("WindowTickerHandler: %w", err)})
+	}
+
+	t.h.Event(event)
+}
+
+func (t *windowTickerHandler) Done() { t.h.Done() }
+
+func windowTickerStreamName(symbol, window string) (string, error) {
+	switch window {
+	case TickerWindow1h, TickerWindow4h, TickerWindow1d:
+		return fmt.Sprintf("%s@ticker_%s", strings.ToLower(symbol), window), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidWindow, window)
+	}
+}
+
+// SubscribeWindowTicker subscribes handler to the rolling window statistics of symbol.
+// Window must be one of TickerWindow1h, TickerWindow4h or TickerWindow1d.
+func (s *Stream) SubscribeWindowTicker(symbol, window string, handler WindowTickerHandler) error {
+	stream, err := windowTickerStreamName(symbol, window)
+	if err != nil {
+		return fmt.Errorf("stream.SubscribeWindowTicker: %w", err)
+	}
+
+	return s.SubscribeRaw([]string{stream}, jsonEventHandler{&windowTickerHandler{handler}})
+}
+
+func (s *Stream) UnsubscribeWindowTicker(symbol, window string) error {
+	stream, err := windowTickerStreamName(symbol, window)
+	if err != nil {
+		return fmt.Errorf("stream.UnsubscribeWindowTicker: %w", err)
+	}
+
+	return s.Unsubscribe(stream)
+}
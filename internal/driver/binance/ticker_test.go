@@ -0,0 +1,236 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testTickerHandler struct {
+	got chan TickerEvent
+}
+
+func (h testTickerHandler) Event(event TickerEvent) { h.got <- event }
+func (h testTickerHandler) Done()                   { close(h.got) }
+
+func Test_tickerArrayHandler_Event(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []TickerEvent
+		wantErr bool
+	}{
+		{
+			"array",
+			`[
+				{"e":"24hrTicker","E":123456789,"s":"BTCUSDT","p":"0.0015","P":"250.00","w":"0.0018","x":"0.0009","c":"0.0025","Q":"10","b":"0.0024","B":"10","a":"0.0026","A":"100","o":"0.0010","h":"0.0025","l":"0.0010","v":"10000","q":"18","O":0,"C":86400000,"F":0,"L":18150,"n":18151},
+				{"e":"24hrTicker","E":123456789,"s":"ETHUSDT","c":"1.5","n":3}
+			]`,
+			[]TickerEvent{
+				{
+					Event:              "24hrTicker",
+					Time:               123456789,
+					Symbol:             "BTCUSDT",
+					PriceChange:        "0.0015",
+					PriceChangePercent: "250.00",
+					WeightedAvgPrice:   "0.0018",
+					FirstTradePrice:    "0.0009",
+					LastPrice:          "0.0025",
+					LastQuantity:       "10",
+					BidPrice:           "0.0024",
+					BidQuantity:        "10",
+					AskPrice:           "0.0026",
+					AskQuantity:        "100",
+					Open:               "0.0010",
+					High:               "0.0025",
+					Low:                "0.0010",
+					BaseVolume:         "10000",
+					QuoteVolume:        "18",
+					OpenTime:           0,
+					CloseTime:          86400000,
+					FirstTradeID:       0,
+					LastTradeID:        18150,
+					Trades:             18151,
+				},
+				{
+					Event:     "24hrTicker",
+					Time:      123456789,
+					Symbol:    "ETHUSDT",
+					LastPrice: "1.5",
+					Trades:    3,
+				},
+			},
+			false,
+		},
+		{
+			"object",
+			`{"e":"24hrTicker","s":"BTCUSDT"}`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := testTickerHandler{make(chan TickerEvent, 10)}
+			h := tickerArrayHandler{h: th}
+
+			defer func() {
+				if err, _ := recover().(error); (err != nil) != tt.wantErr {
+					t.Errorf("tickerArrayHandler.Event() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}()
+
+			h.Event([]byte(tt.data))
+			h.Done()
+
+			var got []TickerEvent
+			for event := range th.got {
+				got = append(got, event)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tickerArrayHandler.Event() = \n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testWindowTickerHandler struct {
+	got chan WindowTickerEvent
+}
+
+func (h testWindowTickerHandler) Event(event WindowTickerEvent) { h.got <- event }
+func (h testWindowTickerHandler) Done()                         { close(h.got) }
+
+func Test_windowTickerHandler_Event(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []WindowTickerEvent
+		wantErr bool
+	}{
+		{
+			"object",
+			`{"e":"1hTicker","E":123456789,"s":"BNBBTC","p":"0.0015","P":"250.00","o":"0.0010","h":"0.0025","l":"0.0010","c":"0.0025","w":"0.0018","v":"10000","q":"18","O":0,"C":3600000,"F":0,"L":18150,"n":18151}`,
+			[]WindowTickerEvent{{
+				Event:              "1hTicker",
+				Time:               123456789,
+				Symbol:             "BNBBTC",
+				PriceChange:        "0.0015",
+				PriceChangePercent: "250.00",
+				Open:               "0.0010",
+				High:               "0.0025",
+				Low:                "0.0010",
+				LastPrice:          "0.0025",
+				WeightedAvgPrice:   "0.0018",
+				BaseVolume:         "10000",
+				QuoteVolume:        "18",
+				OpenTime:           0,
+				CloseTime:          3600000,
+				FirstTradeID:       0,
+				LastTradeID:        18150,
+				Trades:             18151,
+			}},
+			false,
+		},
+		{
+			"array",
+			`[{"e":"1hTicker"}]`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := testWindowTickerHandler{make(chan WindowTickerEvent, 10)}
+			h := windowTickerHandler{h: th}
+
+			defer func() {
+				if err, _ := recover().(error); (err != nil) != tt.wantErr {
+					t.Errorf("windowTickerHandler.Event() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}()
+
+			h.Event([]byte(tt.data))
+			h.Done()
+
+			var got []WindowTickerEvent
+			for event := range th.got {
+				got = append(got, event)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("windowTickerHandler.Event() = \n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_windowTickerStreamName(t *testing.T) {
+	tests := []struct {
+		window  string
+		want    string
+		wantErr error
+	}{
+		{TickerWindow1h, "btcusdt@ticker_1h", nil},
+		{TickerWindow4h, "btcusdt@ticker_4h", nil},
+		{TickerWindow1d, "btcusdt@ticker_1d", nil},
+		{"1m", "", ErrInvalidWindow},
+		{"", "", ErrInvalidWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			got, err := windowTickerStreamName("BTCUSDT", tt.window)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("windowTickerStreamName() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("windowTickerStreamName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeWindowTicker(t *testing.T) {
+	h := testWindowTickerHandler{make(chan WindowTickerEvent, 100)}
+
+	if err := testStream.SubscribeWindowTicker("btcusdt", "2h", h); !errors.Is(err, ErrInvalidWindow) {
+		t.Errorf("SubscribeWindowTicker() error = %v, want %v", err, ErrInvalidWindow)
+	}
+
+	if err := testStream.SubscribeWindowTicker("btcusdt", TickerWindow1h, h); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-h.got:
+		if event.Symbol != "BTCUSDT" {
+			t.Errorf("SubscribeWindowTicker() symbol = %s, want BTCUSDT", event.Symbol)
+		}
+	case <-testCTX.Done():
+		t.Error("SubscribeWindowTicker: no data received")
+	}
+
+	if err := testStream.UnsubscribeWindowTicker("btcusdt", TickerWindow1h); err != nil {
+		t.Fatal(err)
+	}
+
+	for range h.got {
+	}
+}
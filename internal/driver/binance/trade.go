@@ -0,0 +1,117 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+// TradeEvent is a single trade between a buyer and a seller.
+type TradeEvent struct {
+	Event         string `json:"e"` // Event type ("trade")
+	Time          int64  `json:"E"` // Event time
+	Symbol        string `json:"s"`
+	TradeID       int64  `json:"t"`
+	Price         string `json:"p"`
+	Quantity      string `json:"q"`
+	BuyerOrderID  int64  `json:"b"`
+	SellerOrderID int64  `json:"a"`
+	TradeTime     int64  `json:"T"`
+	BuyerMaker    bool   `json:"m"` // Is the buyer the market maker?
+}
+
+// Trade parses the event into the driver abstraction.
+func (e TradeEvent) Trade() (driver.Trade, error) {
+	price, err := ParsePrice(e.Price)
+	if err != nil {
+		return driver.Trade{}, fmt.Errorf("trade event price: %w", err)
+	}
+	qty, err := ParseQuantity(e.Quantity)
+	if err != nil {
+		return driver.Trade{}, fmt.Errorf("trade event quantity: %w", err)
+	}
+
+	return driver.Trade{
+		ID:         e.TradeID,
+		Price:      price.Float64(),
+		Quantity:   qty.Float64(),
+		Time:       time.UnixMilli(e.TradeTime),
+		BuyerMaker: e.BuyerMaker,
+	}, nil
+}
+
+type TradeHandler interface {
+	Event(TradeEvent)
+	Done()
+}
+
+type tradeHandler struct {
+	h TradeHandler
+}
+
+func (t *tradeHandler) Event(data []byte) {
+	var event TradeEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		panic(decodeError{fmt.Errorf("TradeHandler: %w", err)})
+	}
+
+	t.h.Event(event)
+}
+
+func (t *tradeHandler) Done() { t.h.Done() }
+
+// TradeAdapter returns a TradeHandler which parses each event
+// and passes it to the driver.TradeHandler h.
+func TradeAdapter(h driver.TradeHandler) TradeHandler {
+	return driverTradeHandler{h}
+}
+
+type driverTradeHandler struct {
+	h driver.TradeHandler
+}
+
+func (d driverTradeHandler) Event(event TradeEvent) {
+	trade, err := event.Trade()
+	if err != nil {
+		panic(decodeError{err})
+	}
+
+	d.h.Event(trade)
+}
+
+func (d driverTradeHandler) Done() { d.h.Done() }
+
+func tradeStreamName(symbol string) string {
+	return strings.ToLower(symbol) + "@trade"
+}
+
+// SubscribeTrades subscribes handler to the individual trades of symbol.
+// Use SubscribeRaw with "<symbol>@aggTrade" for trades aggregated by order.
+func (s *Stream) SubscribeTrades(symbol string, handler TradeHandler) error {
+	return s.SubscribeRaw([]string{tradeStreamName(symbol)}, jsonEventHandler{&tradeHandler{handler}})
+}
+
+func (s *Stream) UnsubscribeTrades(symbol string) error {
+	return s.Unsubscribe(tradeStreamName(symbol))
+}
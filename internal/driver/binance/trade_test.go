@@ -0,0 +1,171 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package binance
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/muhlemmer/yatgo/internal/driver"
+)
+
+type testTradeHandler struct {
+	got chan TradeEvent
+}
+
+func (h testTradeHandler) Event(event TradeEvent) {
+	h.got <- event
+}
+
+func (h testTradeHandler) Done() {
+	close(h.got)
+}
+
+func newTestTradeHandler(bufLen int) testTradeHandler {
+	return testTradeHandler{
+		got: make(chan TradeEvent, bufLen),
+	}
+}
+
+const testTradeData = `{
+	"e": "trade",
+	"E": 1672515782136,
+	"s": "BNBBTC",
+	"t": 12345,
+	"p": "0.001",
+	"q": "100",
+	"b": 88,
+	"a": 50,
+	"T": 1672515782136,
+	"m": true,
+	"M": true
+}`
+
+func Test_tradeHandler_Event(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    TradeEvent
+		wantErr bool
+	}{
+		{
+			"success",
+			testTradeData,
+			TradeEvent{
+				Event:         "trade",
+				Time:          1672515782136,
+				Symbol:        "BNBBTC",
+				TradeID:       12345,
+				Price:         "0.001",
+				Quantity:      "100",
+				BuyerOrderID:  88,
+				SellerOrderID: 50,
+				TradeTime:     1672515782136,
+				BuyerMaker:    true,
+			},
+			false,
+		},
+		{
+			"error",
+			"!",
+			TradeEvent{},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newTestTradeHandler(1)
+			h := tradeHandler{h: k}
+
+			defer func() {
+				if err, _ := recover().(error); (err != nil) != tt.wantErr {
+					t.Errorf("tradeHandler.Event() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}()
+
+			h.Event([]byte(tt.data))
+			h.h.Done()
+
+			if got := <-k.got; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tradeHandler.Event() = \n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTradeEvent_Trade(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   TradeEvent
+		want    driver.Trade
+		wantErr bool
+	}{
+		{
+			"success",
+			TradeEvent{TradeID: 1, Price: "0.001", Quantity: "100", TradeTime: 1672515782136, BuyerMaker: true},
+			driver.Trade{ID: 1, Price: 0.001, Quantity: 100, Time: time.UnixMilli(1672515782136), BuyerMaker: true},
+			false,
+		},
+		{
+			"price error",
+			TradeEvent{Price: "foo", Quantity: "100"},
+			driver.Trade{},
+			true,
+		},
+		{
+			"quantity error",
+			TradeEvent{Price: "0.001", Quantity: "foo"},
+			driver.Trade{},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.event.Trade()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TradeEvent.Trade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("TradeEvent.Trade() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeTrades(t *testing.T) {
+	h := newTestTradeHandler(100)
+
+	if err := testStream.SubscribeTrades("btcusdt", h); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-h.got:
+	case <-testCTX.Done():
+		t.Error("SubscribeTrades: no data received")
+	}
+
+	if err := testStream.UnsubscribeTrades("btcusdt"); err != nil {
+		t.Fatal(err)
+	}
+
+	for range h.got {
+	}
+}
@@ -0,0 +1,138 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// bufferItem is either a message or a flush marker.
+type bufferItem struct {
+	data    []byte
+	flushed chan struct{}
+}
+
+// Buffered is a JSONHandler which queues messages for a worker goroutine,
+// so that a slow handler does not block the stream's listener.
+type Buffered struct {
+	ctx   context.Context
+	h     JSONHandler
+	queue chan bufferItem
+
+	mtx    sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// Buffer wraps handler, so that messages are delivered in order from a worker goroutine.
+// Up to size messages are buffered, after which Event blocks.
+// Done is called through after the buffered messages are delivered.
+//
+// Panics of handler are recovered and logged to the logger of ctx.
+func Buffer(ctx context.Context, handler JSONHandler, size int) *Buffered {
+	b := &Buffered{
+		ctx:   ctx,
+		h:     handler,
+		queue: make(chan bufferItem, size),
+		done:  make(chan struct{}),
+	}
+	go b.work()
+
+	return b
+}
+
+func (b *Buffered) work() {
+	defer close(b.done)
+
+	for item := range b.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		b.deliver(item.data)
+	}
+
+	b.h.Done()
+}
+
+func (b *Buffered) deliver(data []byte) {
+	defer func() {
+		if x := recover(); x != nil {
+			zerolog.Ctx(b.ctx).Error().Interface("panic", x).Msg("driver.Buffered")
+		}
+	}()
+
+	b.h.Event(data)
+}
+
+// Event queues data for delivery.
+// Messages after Done are dropped.
+func (b *Buffered) Event(data []byte) {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	if !b.closed {
+		b.queue <- bufferItem{data: data}
+	}
+}
+
+// Done stops the worker after the buffered messages are delivered.
+func (b *Buffered) Done() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+}
+
+// Flush blocks until all messages queued before the call are delivered,
+// or ctx is done.
+func (b *Buffered) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	b.mtx.RLock()
+	if b.closed {
+		b.mtx.RUnlock()
+		return b.wait(ctx, b.done)
+	}
+
+	select {
+	case b.queue <- bufferItem{flushed: flushed}:
+		b.mtx.RUnlock()
+	case <-ctx.Done():
+		b.mtx.RUnlock()
+		return ctx.Err()
+	}
+
+	return b.wait(ctx, flushed)
+}
+
+func (b *Buffered) wait(ctx context.Context, c <-chan struct{}) error {
+	select {
+	case <-c:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,86 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type slowHandler struct {
+	recordHandler
+	delay time.Duration
+}
+
+func (h *slowHandler) Event(data []byte) {
+	time.Sleep(h.delay)
+	if string(data) == "panic" {
+		panic("slowHandler")
+	}
+	h.recordHandler.Event(data)
+}
+
+func TestBuffered_Flush(t *testing.T) {
+	h := &slowHandler{delay: time.Millisecond}
+	b := Buffer(testCTX, h, 100)
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		want = append(want, fmt.Sprint(i))
+		b.Event([]byte(fmt.Sprint(i)))
+	}
+	b.Event([]byte("panic"))
+
+	if err := b.Flush(testCTX); err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := h.get(); !reflect.DeepEqual(events, want) {
+		t.Errorf("Buffered.Flush() events = %v, want %v", events, want)
+	}
+
+	b.Done()
+	if err := b.Flush(testCTX); err != nil {
+		t.Fatal(err)
+	}
+	if _, done := h.get(); !done {
+		t.Error("Buffered.Flush() after Done did not wait for Done")
+	}
+
+	b.Event([]byte("late"))
+	b.Done()
+}
+
+func TestBuffered_Flush_ctx(t *testing.T) {
+	h := &slowHandler{delay: time.Second}
+	b := Buffer(testCTX, h, 1)
+	defer b.Done()
+
+	b.Event([]byte("slow"))
+
+	ctx, cancel := context.WithTimeout(testCTX, 10*time.Millisecond)
+	defer cancel()
+
+	if err := b.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Buffered.Flush() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -19,11 +19,18 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package driver
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -33,17 +40,223 @@ import (
 type Client struct {
 	http.Client
 	Hosts []string
+
+	// ProbePath is requested on each host by ProbeHosts.
+	// Defaults to "/".
+	ProbePath string
+
+	// TraceHeader carries the trace ID of the request context, see WithTraceID.
+	// Defaults to DefaultTraceHeader.
+	TraceHeader string
+
+	mtx sync.RWMutex // guards Hosts during ProbeHosts
+}
+
+// CurrentHosts returns a copy of Hosts, in their current order.
+func (c *Client) CurrentHosts() []string {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return append([]string(nil), c.Hosts...)
+}
+
+type probe struct {
+	host    string
+	latency time.Duration
+	err     error
+}
+
+func (c *Client) probe(ctx context.Context, host string) probe {
+	path := c.ProbePath
+	if path == "" {
+		path = "/"
+	}
+
+	u := url.URL{Scheme: "https", Host: host, Path: path}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return probe{host: host, err: err}
+	}
+
+	start := time.Now()
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return probe{host: host, err: err}
+	}
+	resp.Body.Close()
+
+	p := probe{host: host, latency: time.Since(start)}
+	if resp.StatusCode >= 500 {
+		p.err = fmt.Errorf("status %s", resp.Status)
+	}
+
+	return p
+}
+
+// ProbeHosts measures the latency of a request to each host concurrently,
+// and reorders Hosts fastest first. Hosts which fail to respond,
+// or respond with a server error, are moved to the end.
+// It is safe to call periodically, concurrent with other requests.
+// HostErrors is returned when all hosts failed, in which case the order is kept.
+func (c *Client) ProbeHosts(ctx context.Context) error {
+	hosts := c.CurrentHosts()
+	probes := make([]probe, len(hosts))
+
+	var wg sync.WaitGroup
+	wg.Add(len(hosts))
+
+	for i, host := range hosts {
+		go func(i int, host string) {
+			defer wg.Done()
+			probes[i] = c.probe(ctx, host)
+		}(i, host)
+	}
+	wg.Wait()
+
+	sort.SliceStable(probes, func(i, j int) bool {
+		if (probes[i].err == nil) != (probes[j].err == nil) {
+			return probes[i].err == nil
+		}
+		return probes[i].latency < probes[j].latency
+	})
+
+	var errs HostErrors
+	order := make([]string, len(probes))
+
+	for i, p := range probes {
+		order[i] = p.host
+		if p.err != nil {
+			errs = append(errs, HostError{p.host, p.err})
+		}
+	}
+
+	zerolog.Ctx(ctx).Debug().Strs("hosts", order).Int("failed", len(errs)).Msg("client ProbeHosts")
+
+	if len(errs) == len(probes) && len(errs) > 0 {
+		return errs
+	}
+
+	c.mtx.Lock()
+	c.Hosts = order
+	c.mtx.Unlock()
+
+	return nil
+}
+
+// DefaultTraceHeader is the request header used for trace IDs,
+// when Client.TraceHeader is empty.
+const DefaultTraceHeader = "X-Trace-Id"
+
+type traceIDKey struct{}
+
+// WithTraceID returns a child context carrying id,
+// which is sent as request header and logged with each request.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
 }
 
-func (c *Client) tryRequest(ctx context.Context, method string, u url.URL, body io.Reader) (resp *http.Response, err error) {
-	for _, ep := range c.Hosts {
+// TraceID returns the trace ID of ctx, if any.
+func TraceID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(traceIDKey{}).(string)
+	return id, ok
+}
+
+func (c *Client) traceHeader() string {
+	if c.TraceHeader == "" {
+		return DefaultTraceHeader
+	}
+	return c.TraceHeader
+}
+
+// ErrNoHosts is returned for requests on a Client without Hosts.
+var ErrNoHosts = errors.New("driver: no hosts configured")
+
+// HostError is the failure of a request on a single host.
+type HostError struct {
+	Host string
+	Err  error
+}
+
+func (e HostError) Error() string { return fmt.Sprintf("%s: %v", e.Host, e.Err) }
+func (e HostError) Unwrap() error { return e.Err }
+
+// HostErrors is returned when the request failed on all hosts.
+// It matches errors.Is and errors.As if any of the host errors does.
+type HostErrors []HostError
+
+func (e HostErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, he := range e {
+		msgs[i] = he.Error()
+	}
+
+	return "all hosts failed: " + strings.Join(msgs, "; ")
+}
+
+func (e HostErrors) Is(target error) bool {
+	for _, he := range e {
+		if errors.Is(he, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e HostErrors) As(target interface{}) bool {
+	for _, he := range e {
+		if errors.As(he, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// tryRequest sends the request to each of the current hosts until one succeeds,
+// see tryHosts.
+func (c *Client) tryRequest(ctx context.Context, method string, u url.URL, header http.Header, body []byte) (*http.Response, error) {
+	return c.tryHosts(ctx, c.CurrentHosts(), method, u, header, body)
+}
+
+// tryHosts sends the request to each host until one succeeds.
+// A fresh reader of body is used for every attempt,
+// so that each host receives the complete body.
+// When the last host returns a server error, its response is returned.
+// When the last host fails with an error, HostErrors with the failures of all hosts is returned.
+// header is added to every request.
+func (c *Client) tryHosts(ctx context.Context, hosts []string, method string, u url.URL, header http.Header, body []byte) (resp *http.Response, err error) {
+	var errs HostErrors
+
+	if len(hosts) == 0 {
+		return nil, ErrNoHosts
+	}
+
+	traceID, traced := TraceID(ctx)
+
+	for i, ep := range hosts {
 
 		u.Host = ep
-		logger := zerolog.Ctx(ctx).With().Stringer("url", &u).Logger()
+		logCtx := zerolog.Ctx(ctx).With().Stringer("url", &u)
+		if traced {
+			logCtx = logCtx.Str("trace_id", traceID)
+		}
+		logger := logCtx.Logger()
 
-		req, re := http.NewRequestWithContext(ctx, method, u.String(), body)
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+
+		req, re := http.NewRequestWithContext(ctx, method, u.String(), r)
 		if re != nil {
-			return nil, fmt.Errorf("client Get: %w", err)
+			return nil, fmt.Errorf("client %s: %w", method, re)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if traced {
+			req.Header.Set(c.traceHeader(), traceID)
 		}
 
 		resp, err = c.Client.Do(req)
@@ -58,16 +271,30 @@ func (c *Client) tryRequest(ctx context.Context, method string, u url.URL, body
 			le.Msg("client response headers")
 		}
 
-		logger.Err(err).Msg("client Get")
+		logger.Err(err).Msg("client " + method)
 
 		// In case of a connection or server-side error,
 		// we are just going to retry the next end-point.
 		if err == nil && resp.StatusCode < 500 {
 			break
 		}
+
+		if err != nil {
+			errs = append(errs, HostError{ep, err})
+			continue
+		}
+
+		errs = append(errs, HostError{ep, fmt.Errorf("status %s", resp.Status)})
+		if i < len(hosts)-1 {
+			resp.Body.Close()
+		}
 	}
 
-	return resp, err
+	if err != nil {
+		return nil, errs
+	}
+
+	return resp, nil
 }
 
 // Get (re)tries a HTTP request against all configured hosts, using path and URL encoded values.
@@ -78,5 +305,71 @@ func (c *Client) Get(ctx context.Context, path string, values url.Values) (resp
 		Scheme:   "https",
 		Path:     path,
 		RawQuery: values.Encode(),
-	}, nil)
+	}, nil, nil)
+}
+
+// Send (re)tries a HTTP request against all configured hosts, like Get.
+// rawQuery is sent as is, for APIs which sign the exact query string.
+// header is added to the request, body may be nil.
+// The body is read into memory first, so that every host receives it completely.
+func (c *Client) Send(ctx context.Context, method, path, rawQuery string, header http.Header, body io.Reader) (*http.Response, error) {
+	return c.send(ctx, c.CurrentHosts(), method, path, rawQuery, header, body)
+}
+
+// SendOnce is like Send, but only tries the first host, without fallback.
+// Use it for requests which must not be repeated, such as placing an order:
+// a server error does not tell if the request was executed.
+func (c *Client) SendOnce(ctx context.Context, method, path, rawQuery string, header http.Header, body io.Reader) (*http.Response, error) {
+	hosts := c.CurrentHosts()
+	if len(hosts) > 1 {
+		hosts = hosts[:1]
+	}
+
+	return c.send(ctx, hosts, method, path, rawQuery, header, body)
+}
+
+func (c *Client) send(ctx context.Context, hosts []string, method, path, rawQuery string, header http.Header, body io.Reader) (*http.Response, error) {
+	var data []byte
+
+	if body != nil {
+		var err error
+		if data, err = ioutil.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("client %s: read body: %w", method, err)
+		}
+	}
+
+	return c.tryHosts(ctx, hosts, method, url.URL{
+		Scheme:   "https",
+		Path:     path,
+		RawQuery: rawQuery,
+	}, header, data)
+}
+
+// doBody sends body with method and its Content-Type.
+func (c *Client) doBody(ctx context.Context, method, path string, values url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	var header http.Header
+	if body != nil && contentType != "" {
+		header = http.Header{"Content-Type": {contentType}}
+	}
+
+	return c.Send(ctx, method, path, values.Encode(), header, body)
+}
+
+// Post (re)tries a HTTP POST request with body against all configured hosts, like Get.
+// The body is read into memory first, so that every host receives it completely.
+// A POST which is not idempotent should use SendOnce instead.
+// contentType is sent as the Content-Type header; body may be nil.
+func (c *Client) Post(ctx context.Context, path string, values url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	return c.doBody(ctx, http.MethodPost, path, values, contentType, body)
+}
+
+// Put (re)tries a HTTP PUT request with body against all configured hosts, like Post.
+func (c *Client) Put(ctx context.Context, path string, values url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	return c.doBody(ctx, http.MethodPut, path, values, contentType, body)
+}
+
+// Delete (re)tries a HTTP DELETE request against all configured hosts, like Post.
+// Most APIs take the parameters as URL encoded values and no body.
+func (c *Client) Delete(ctx context.Context, path string, values url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	return c.doBody(ctx, http.MethodDelete, path, values, contentType, body)
 }
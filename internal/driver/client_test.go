@@ -19,11 +19,21 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package driver
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -35,7 +45,7 @@ func TestClient_tryRequest(t *testing.T) {
 		ctx    context.Context
 		method string
 		u      url.URL
-		body   io.Reader
+		body   []byte
 	}
 	tests := []struct {
 		name           string
@@ -64,6 +74,21 @@ func TestClient_tryRequest(t *testing.T) {
 			0,
 			true,
 		},
+		{
+			"no hosts",
+			nil,
+			args{
+				logger.WithContext(testCTX),
+				http.MethodGet,
+				url.URL{
+					Scheme: "https",
+					Path:   "api/v3/ping",
+				},
+				nil,
+			},
+			0,
+			true,
+		},
 		{
 			"lookup failures",
 			[]string{
@@ -129,7 +154,7 @@ func TestClient_tryRequest(t *testing.T) {
 			c := &Client{
 				Hosts: tt.Hosts,
 			}
-			got, err := c.tryRequest(tt.args.ctx, tt.args.method, tt.args.u, tt.args.body)
+			got, err := c.tryRequest(tt.args.ctx, tt.args.method, tt.args.u, nil, tt.args.body)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Client.tryRequest() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -141,6 +166,193 @@ func TestClient_tryRequest(t *testing.T) {
 	}
 }
 
+func TestClient_tryRequest_body(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	var (
+		calls int
+		got   []byte
+	)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		got = body
+	}))
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	c := &Client{
+		Client: *srv.Client(),
+		Hosts:  []string{host, host},
+	}
+
+	want := "symbol=BTCUSDT&side=BUY"
+
+	resp, err := c.tryRequest(logger.WithContext(testCTX), http.MethodPost, url.URL{Scheme: "https", Path: "api/v3/order"}, nil, []byte(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if calls != 2 {
+		t.Errorf("Client.tryRequest() calls = %d, want 2", calls)
+	}
+	if string(got) != want {
+		t.Errorf("Client.tryRequest() body = %q, want %q", got, want)
+	}
+}
+
+func TestClient_tryRequest_traceID(t *testing.T) {
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	tests := []struct {
+		name   string
+		header string
+		ctx    context.Context
+		want   string
+	}{
+		{"no trace", "", logger.WithContext(testCTX), ""},
+		{"default header", "", WithTraceID(logger.WithContext(testCTX), "abc"), "abc"},
+		{"custom header", "X-Request-Id", WithTraceID(logger.WithContext(testCTX), "def"), "def"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+
+			header := tt.header
+			if header == "" {
+				header = DefaultTraceHeader
+			}
+
+			var got string
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get(header)
+			}))
+			defer srv.Close()
+
+			c := &Client{
+				Client:      *srv.Client(),
+				Hosts:       []string{srv.Listener.Addr().String()},
+				TraceHeader: tt.header,
+			}
+
+			resp, err := c.tryRequest(tt.ctx, http.MethodGet, url.URL{Scheme: "https", Path: "api/v3/ping"}, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got != tt.want {
+				t.Errorf("Client.tryRequest() %s = %q, want %q", header, got, tt.want)
+			}
+			if logged := strings.Contains(logs.String(), `"trace_id":"`+tt.want+`"`); logged != (tt.want != "") {
+				t.Errorf("Client.tryRequest() log = %s, want trace_id %q", logs.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_tryRequest_hostErrors(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	unavailable := srv.Listener.Addr().String()
+
+	c := &Client{
+		Client: *srv.Client(),
+		Hosts:  []string{unavailable, "tja", "127.0.0.1:1"},
+	}
+
+	_, err := c.tryRequest(logger.WithContext(testCTX), http.MethodGet, url.URL{Scheme: "https", Path: "api/v3/ping"}, nil, nil)
+
+	var hostErrs HostErrors
+	if !errors.As(err, &hostErrs) {
+		t.Fatalf("Client.tryRequest() error = %v, want HostErrors", err)
+	}
+	if len(hostErrs) != len(c.Hosts) {
+		t.Errorf("Client.tryRequest() errors = %d, want %d", len(hostErrs), len(c.Hosts))
+	}
+	for _, host := range c.Hosts {
+		if !strings.Contains(err.Error(), host+": ") {
+			t.Errorf("Client.tryRequest() error %q is missing host %s", err, host)
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		t.Errorf("Client.tryRequest() error = %v, want a %T", err, dnsErr)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Client.tryRequest() error = %v, want %v", err, syscall.ECONNREFUSED)
+	}
+}
+
+func TestClient_ProbeHosts(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	newHost := func(delay time.Duration, status int) string {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v3/ping" {
+				t.Errorf("probe path = %s", r.URL.Path)
+			}
+			time.Sleep(delay)
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv.Listener.Addr().String()
+	}
+
+	var (
+		dead = newHost(0, http.StatusServiceUnavailable)
+		slow = newHost(100*time.Millisecond, http.StatusOK)
+		fast = newHost(0, http.StatusOK)
+	)
+
+	c := &Client{
+		Client: http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+		Hosts:     []string{dead, "tja", slow, fast},
+		ProbePath: "/api/v3/ping",
+	}
+
+	if err := c.ProbeHosts(logger.WithContext(testCTX)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := c.CurrentHosts()
+	if want := []string{fast, slow}; !reflect.DeepEqual(got[:2], want) {
+		t.Errorf("Client.ProbeHosts() order = %v, want %v first", got, want)
+	}
+	if tail := got[2:]; !(reflect.DeepEqual(tail, []string{dead, "tja"}) || reflect.DeepEqual(tail, []string{"tja", dead})) {
+		t.Errorf("Client.ProbeHosts() order = %v, want failed hosts last", got)
+	}
+
+	c.Hosts = []string{dead, "tja"}
+
+	var hostErrs HostErrors
+	if err := c.ProbeHosts(logger.WithContext(testCTX)); !errors.As(err, &hostErrs) || len(hostErrs) != 2 {
+		t.Errorf("Client.ProbeHosts() error = %v, want HostErrors of 2 hosts", err)
+	}
+}
+
 func TestClient_Get(t *testing.T) {
 	logger := zerolog.New(zerolog.NewTestWriter(t))
 
@@ -219,3 +431,170 @@ func TestClient_Get(t *testing.T) {
 		})
 	}
 }
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestClient_bodyMethods(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	type request struct {
+		method      string
+		contentType string
+		query       string
+		body        string
+	}
+
+	const (
+		contentType = "application/x-www-form-urlencoded"
+		body        = "symbol=BTCUSDT&side=BUY"
+	)
+
+	tests := []struct {
+		name string
+		do   func(c *Client, ctx context.Context, body io.Reader) (*http.Response, error)
+		body io.Reader
+		want request
+	}{
+		{
+			"post",
+			func(c *Client, ctx context.Context, body io.Reader) (*http.Response, error) {
+				return c.Post(ctx, "api/v3/order", nil, contentType, body)
+			},
+			strings.NewReader(body),
+			request{http.MethodPost, contentType, "", body},
+		},
+		{
+			"put",
+			func(c *Client, ctx context.Context, body io.Reader) (*http.Response, error) {
+				return c.Put(ctx, "api/v3/userDataStream", nil, contentType, body)
+			},
+			strings.NewReader(body),
+			request{http.MethodPut, contentType, "", body},
+		},
+		{
+			"delete without body",
+			func(c *Client, ctx context.Context, body io.Reader) (*http.Response, error) {
+				return c.Delete(ctx, "api/v3/order", url.Values{"orderId": {"1"}}, contentType, body)
+			},
+			nil,
+			request{http.MethodDelete, "", "orderId=1", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reqs []request
+
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+
+				reqs = append(reqs, request{r.Method, r.Header.Get("Content-Type"), r.URL.RawQuery, string(b)})
+				if len(reqs) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer srv.Close()
+
+			host := srv.Listener.Addr().String()
+			c := &Client{
+				Client: *srv.Client(),
+				Hosts:  []string{host, host},
+			}
+
+			resp, err := tt.do(c, logger.WithContext(testCTX), tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+
+			// The body is sent again to the fallback host.
+			want := []request{tt.want, tt.want}
+			if !reflect.DeepEqual(reqs, want) {
+				t.Errorf("requests = %v, want %v", reqs, want)
+			}
+		})
+	}
+}
+
+func TestClient_Post_bodyError(t *testing.T) {
+	c := &Client{Hosts: []string{"localhost"}}
+
+	_, err := c.Post(testCTX, "api/v3/order", nil, "text/plain", errReader{io.ErrUnexpectedEOF})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Client.Post() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestClient_Send(t *testing.T) {
+	const rawQuery = "symbol=LTCBTC&side=BUY&signature=abc"
+
+	var (
+		gotQuery string
+		gotKey   string
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery, gotKey = r.URL.RawQuery, r.Header.Get("X-Api-Key")
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		Client: *srv.Client(),
+		Hosts:  []string{srv.Listener.Addr().String()},
+	}
+
+	resp, err := c.Send(testCTX, http.MethodGet, "api/v3/account", rawQuery, http.Header{"X-Api-Key": {"key"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// The query is not re-encoded, which would reorder it.
+	if gotQuery != rawQuery {
+		t.Errorf("Client.Send() query = %q, want %q", gotQuery, rawQuery)
+	}
+	if gotKey != "key" {
+		t.Errorf("Client.Send() header = %q, want %q", gotKey, "key")
+	}
+}
+
+func TestClient_SendOnce(t *testing.T) {
+	var calls int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	c := &Client{
+		Client: *srv.Client(),
+		Hosts:  []string{host, host},
+	}
+
+	resp, err := c.SendOnce(testCTX, http.MethodPost, "api/v3/order", "", nil, strings.NewReader("symbol=BTCUSDT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// The server error is returned, instead of trying the fallback host.
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Client.SendOnce() status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if calls != 1 {
+		t.Errorf("Client.SendOnce() calls = %d, want 1", calls)
+	}
+
+	c.Hosts = nil
+	if _, err = c.SendOnce(testCTX, http.MethodPost, "api/v3/order", "", nil, nil); !errors.Is(err, ErrNoHosts) {
+		t.Errorf("Client.SendOnce() error = %v, want %v", err, ErrNoHosts)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := NewFakeClock(start)
+
+	var fired []string
+	c.AfterFunc(2*time.Second, func() { fired = append(fired, "2s") })
+	c.AfterFunc(time.Second, func() { fired = append(fired, "1s") })
+	stopped := c.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	after := c.After(3 * time.Second)
+
+	if !stopped.Stop() {
+		t.Error("Timer.Stop() = false for a pending timer")
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	if want := []string{"1s"}; !reflect.DeepEqual(fired, want) {
+		t.Errorf("FakeClock.Advance() fired %v, want %v", fired, want)
+	}
+
+	c.Advance(2 * time.Second)
+	if want := []string{"1s", "2s"}; !reflect.DeepEqual(fired, want) {
+		t.Errorf("FakeClock.Advance() fired %v, want %v", fired, want)
+	}
+
+	select {
+	case got := <-after:
+		if want := start.Add(3500 * time.Millisecond); !got.Equal(want) {
+			t.Errorf("FakeClock.After() = %v, want %v", got, want)
+		}
+	default:
+		t.Error("FakeClock.After() did not fire")
+	}
+
+	if stopped.Stop() {
+		t.Error("Timer.Stop() = true for a stopped timer")
+	}
+}
+
+func TestClockOrReal(t *testing.T) {
+	if _, ok := ClockOrReal(nil).(RealClock); !ok {
+		t.Error("ClockOrReal(nil) is not RealClock")
+	}
+	fake := NewFakeClock(time.Time{})
+	if ClockOrReal(fake) != fake {
+		t.Error("ClockOrReal() did not return the passed clock")
+	}
+}
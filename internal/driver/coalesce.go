@@ -0,0 +1,136 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type coalescer struct {
+	ctx      context.Context
+	h        JSONHandler
+	interval time.Duration
+
+	mtx     sync.Mutex
+	latest  []byte
+	last    time.Time // Last delivery to h
+	timer   *time.Timer
+	stopped bool
+}
+
+// Coalesce wraps handler, so that it receives at most one message per interval.
+// The first message is delivered directly. Messages arriving within the interval
+// replace each other, and only the latest is delivered once the interval has passed.
+// This suits streams like depth or ticker updates, where only the current state matters.
+// A pending message is delivered before Done is called through.
+//
+// Delayed messages are delivered from a timer goroutine,
+// where panics of handler are recovered and logged to the logger of ctx.
+func Coalesce(ctx context.Context, handler JSONHandler, interval time.Duration) JSONHandler {
+	return &coalescer{
+		ctx:      ctx,
+		h:        handler,
+		interval: interval,
+	}
+}
+
+func (c *coalescer) Event(data []byte) {
+	c.mtx.Lock()
+
+	if c.stopped {
+		c.mtx.Unlock()
+		return
+	}
+
+	c.latest = data
+	if c.timer != nil {
+		c.mtx.Unlock()
+		return
+	}
+
+	if wait := c.interval - time.Since(c.last); wait > 0 {
+		c.timer = time.AfterFunc(wait, c.flush)
+		c.mtx.Unlock()
+		return
+	}
+
+	data = c.take()
+	c.mtx.Unlock()
+
+	c.h.Event(data)
+}
+
+// take the latest message for delivery. Must be called with mtx locked.
+func (c *coalescer) take() []byte {
+	data := c.latest
+	c.latest = nil
+
+	if data != nil {
+		c.last = time.Now()
+	}
+
+	return data
+}
+
+func (c *coalescer) flush() {
+	c.mtx.Lock()
+
+	c.timer = nil
+
+	var data []byte
+	if !c.stopped {
+		data = c.take()
+	}
+
+	c.mtx.Unlock()
+
+	if data == nil {
+		return
+	}
+
+	defer func() {
+		if x := recover(); x != nil {
+			zerolog.Ctx(c.ctx).Error().Interface("panic", x).Msg("driver.Coalesce handler panic recover")
+		}
+	}()
+
+	c.h.Event(data)
+}
+
+func (c *coalescer) Done() {
+	c.mtx.Lock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	data := c.take()
+	c.stopped = true
+
+	c.mtx.Unlock()
+
+	if data != nil {
+		c.h.Event(data)
+	}
+	c.h.Done()
+}
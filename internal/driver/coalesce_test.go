@@ -0,0 +1,126 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type recordHandler struct {
+	mtx    sync.Mutex
+	events []string
+	done   bool
+}
+
+func (h *recordHandler) Event(data []byte) {
+	h.mtx.Lock()
+	h.events = append(h.events, string(data))
+	h.mtx.Unlock()
+}
+
+func (h *recordHandler) Done() {
+	h.mtx.Lock()
+	h.done = true
+	h.mtx.Unlock()
+}
+
+func (h *recordHandler) get() ([]string, bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return append([]string(nil), h.events...), h.done
+}
+
+func TestCoalesce(t *testing.T) {
+	inner := new(recordHandler)
+	c := Coalesce(testCTX, inner, 50*time.Millisecond)
+
+	burst := func(from, to int) {
+		for i := from; i <= to; i++ {
+			c.Event([]byte(strconv.Itoa(i)))
+		}
+	}
+
+	burst(1, 10)
+	if got, _ := inner.get(); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("Coalesce() first delivery = %v, want [1]", got)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if got, _ := inner.get(); !reflect.DeepEqual(got, []string{"1", "10"}) {
+		t.Errorf("Coalesce() after interval = %v, want [1 10]", got)
+	}
+
+	burst(11, 20)
+	burst(21, 30)
+	c.Done()
+
+	got, done := inner.get()
+	if want := []string{"1", "10", "11", "30"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Coalesce() after Done = %v, want %v", got, want)
+	}
+	if !done {
+		t.Error("Coalesce() Done not called through")
+	}
+
+	c.Event([]byte("31"))
+	time.Sleep(100 * time.Millisecond)
+	if got, _ := inner.get(); len(got) != 4 {
+		t.Errorf("Coalesce() delivered after Done: %v", got)
+	}
+}
+
+type panicJSONHandler struct {
+	events chan []byte
+}
+
+func (h panicJSONHandler) Event(data []byte) {
+	h.events <- data
+	panic("foo")
+}
+
+func (panicJSONHandler) Done() {}
+
+func TestCoalesce_panic(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	inner := panicJSONHandler{make(chan []byte, 2)}
+
+	c := Coalesce(logger.WithContext(testCTX), inner, 10*time.Millisecond)
+
+	func() {
+		defer func() { recover() }()
+		c.Event([]byte("1"))
+	}()
+	c.Event([]byte("2"))
+
+	// the timer delivery panics, which must not crash the process
+	if got := <-inner.events; string(got) != "1" {
+		t.Errorf("Coalesce() first = %s, want 1", got)
+	}
+	if got := <-inner.events; string(got) != "2" {
+		t.Errorf("Coalesce() delayed = %s, want 2", got)
+	}
+	time.Sleep(10 * time.Millisecond)
+}
@@ -31,13 +31,21 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func DialWebsocket(ctx context.Context, dialer *websocket.Dialer, endpoint string, requestHeader http.Header) (*websocket.Conn, error) {
+// DialWebsocket dials the websocket endpoint with a 5 second time-out.
+// A nil dialer uses websocket.DefaultDialer.
+// The handshake response is returned alongside the connection,
+// so callers can inspect the negotiated extensions.
+func DialWebsocket(ctx context.Context, dialer *websocket.Dialer, endpoint string, requestHeader http.Header) (*websocket.Conn, *http.Response, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	logger := zerolog.Ctx(ctx).With().Str("endpoint", endpoint).Logger()
 
-	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, endpoint, requestHeader)
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, endpoint, requestHeader)
 
 	if resp != nil {
 		body, _ := ioutil.ReadAll(resp.Body)
@@ -46,16 +54,19 @@ func DialWebsocket(ctx context.Context, dialer *websocket.Dialer, endpoint strin
 	logger.Err(err).Msg("driver.DialWebsocket")
 
 	if err != nil {
-		return nil, fmt.Errorf("driver.DialWebsocket: %w", err)
+		return nil, resp, fmt.Errorf("driver.DialWebsocket: %w", err)
 	}
 
-	return conn, nil
+	return conn, resp, nil
 }
 
 // JSONStreamHandler handels incomming JSON messages on a websocket.
 type JSONHandler interface {
 	// Event is called on each complete JSON message.
 	// Panics during execution must not infuence the socket listener.
+	// The handler owns data: callers pass a copy which is not reused,
+	// so it may be retained after Event returns without copying.
+	// Callers which reuse their buffers must copy before calling Event.
 	Event(data []byte)
 
 	// Done is called when the orignating stream is closed or unsubscribed.
@@ -64,6 +75,35 @@ type JSONHandler interface {
 	Done()
 }
 
+// ContextHandler is like JSONHandler, but receives the context
+// of the originating stream, which is canceled when the stream closes.
+// Handlers can use it to tie spawned work to the stream's lifecycle.
+type ContextHandler interface {
+	// Event is called on each complete JSON message.
+	// Panics during execution must not infuence the socket listener.
+	// The handler owns data, like in JSONHandler.
+	Event(ctx context.Context, data []byte)
+
+	// Done is called when the orignating stream is closed or unsubscribed.
+	Done(ctx context.Context)
+}
+
+// ContextAdapter returns a ContextHandler which ignores the context
+// and calls h.
+func ContextAdapter(h JSONHandler) ContextHandler {
+	return contextHandler{h}
+}
+
+type contextHandler struct {
+	h JSONHandler
+}
+
+func (c contextHandler) Event(ctx context.Context, data []byte) { TimedEvent(ctx, c.h, data) }
+func (c contextHandler) Done(context.Context)                   { c.h.Done() }
+
+// Unwrap returns the adapted JSONHandler.
+func (c contextHandler) Unwrap() JSONHandler { return c.h }
+
 // SyncMap is a type-safe generic wrapper of sync.Map
 type SyncMap[K, V any] struct {
 	sync.Map
@@ -79,7 +79,7 @@ func TestDialWebsocket(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ws, err := DialWebsocket(tt.args.ctx, websocket.DefaultDialer, tt.args.endpoint, nil)
+			ws, _, err := DialWebsocket(tt.args.ctx, websocket.DefaultDialer, tt.args.endpoint, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DialWebsocket() error = %v, wantErr %v", err, tt.wantErr)
 				return
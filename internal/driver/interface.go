@@ -18,6 +18,8 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
 package driver
 
+import "time"
+
 type ClosingPrice struct {
 	Price  float64
 	Closed bool // Period is fininshed
@@ -32,3 +34,16 @@ type ClosingPriceStreamer interface {
 	SubscribeClosingPrices(symbol string, interval string, handler ClosingPriceHandler) error
 	UnsubscribeClosingPrices(symbol string, interval string) error
 }
+
+type Trade struct {
+	ID         int64
+	Price      float64
+	Quantity   float64
+	Time       time.Time
+	BuyerMaker bool // Buyer placed the resting order, the trade was a sell
+}
+
+type TradeHandler interface {
+	Event(Trade)
+	Done()
+}
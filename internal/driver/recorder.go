@@ -0,0 +1,116 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Recording is a single recorded stream message.
+type Recording struct {
+	Time   time.Time       `json:"time"`
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Recorder writes stream messages as newline-delimited JSON Recordings.
+// It is safe for use by multiple RecorderHandlers.
+type Recorder struct {
+	mtx sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+func (r *Recorder) record(stream string, data []byte) {
+	rec := Recording{
+		Time:   time.Now(),
+		Stream: stream,
+		Data:   data,
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.err == nil {
+		r.err = r.enc.Encode(rec)
+	}
+}
+
+// Err returns the first write error.
+// Messages are no longer recorded after an error.
+func (r *Recorder) Err() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.err
+}
+
+// Handler returns a RecorderHandler recording the messages of stream
+// and forwarding them to inner, which may be nil.
+func (r *Recorder) Handler(stream string, inner JSONHandler) *RecorderHandler {
+	return &RecorderHandler{r, stream, inner}
+}
+
+// RecorderHandler records each message before passing it on.
+type RecorderHandler struct {
+	r      *Recorder
+	stream string
+	h      JSONHandler
+}
+
+func (h *RecorderHandler) Event(data []byte) {
+	h.r.record(h.stream, data)
+
+	if h.h != nil {
+		h.h.Event(data)
+	}
+}
+
+// Done is forwarded to the inner handler. The Recorder's writer is not closed.
+func (h *RecorderHandler) Done() {
+	if h.h != nil {
+		h.h.Done()
+	}
+}
+
+// RecordingReader reads the Recordings written by a Recorder.
+type RecordingReader struct {
+	dec *json.Decoder
+}
+
+// NewRecordingReader returns a RecordingReader reading from r.
+func NewRecordingReader(r io.Reader) *RecordingReader {
+	return &RecordingReader{json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Next returns the next Recording, or io.EOF at the end of the input.
+func (r *RecordingReader) Next() (Recording, error) {
+	var rec Recording
+	err := r.dec.Decode(&rec)
+	return rec, err
+}
@@ -0,0 +1,91 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+
+	inner := new(recordHandler)
+	klines := r.Handler("btcusdt@kline_1m", inner)
+	trades := r.Handler("btcusdt@trade", nil)
+
+	messages := []Recording{
+		{Stream: "btcusdt@kline_1m", Data: []byte(`{"k":1}`)},
+		{Stream: "btcusdt@trade", Data: []byte(`{"t":2}`)},
+		{Stream: "btcusdt@kline_1m", Data: []byte(`{"k":3}`)},
+	}
+	for _, m := range messages {
+		if m.Stream == "btcusdt@trade" {
+			trades.Event(m.Data)
+		} else {
+			klines.Event(m.Data)
+		}
+	}
+	klines.Done()
+	trades.Done()
+
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if events, done := inner.get(); !reflect.DeepEqual(events, []string{`{"k":1}`, `{"k":3}`}) || !done {
+		t.Errorf("RecorderHandler forwarded %v, done %v", events, done)
+	}
+
+	reader := NewRecordingReader(&buf)
+	for i, want := range messages {
+		got, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Time.IsZero() {
+			t.Errorf("Recording %d has no time", i)
+		}
+		if got.Stream != want.Stream || string(got.Data) != string(want.Data) {
+			t.Errorf("Recording %d = %s %s, want %s %s", i, got.Stream, got.Data, want.Stream, want.Data)
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("RecordingReader.Next() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestRecorder_Err(t *testing.T) {
+	r := NewRecorder(errWriter{})
+	h := r.Handler("stream", nil)
+
+	h.Event([]byte(`{}`))
+	h.Event([]byte(`{}`))
+
+	if err := r.Err(); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Recorder.Err() = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
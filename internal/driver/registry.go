@@ -0,0 +1,120 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Driver is the set of capabilities an exchange driver exposes to apps.
+type Driver interface {
+	ClosingPriceStreamer
+}
+
+// Config is passed to a Constructor when a driver is opened.
+type Config struct {
+	// Context is the lifetime of the driver and its connections.
+	Context context.Context
+
+	// Options are driver specific settings, such as a region.
+	Options map[string]string
+}
+
+// Constructor creates a Driver from cfg.
+type Constructor func(cfg Config) (Driver, error)
+
+// ErrUnknownDriver is returned when opening a driver name which is not registered.
+var ErrUnknownDriver = errors.New("unknown driver")
+
+// Registry of driver constructors by name.
+// The zero value is ready for use.
+type Registry struct {
+	mtx          sync.RWMutex
+	constructors map[string]Constructor
+}
+
+// Register the constructor under name.
+// It panics if constructor is nil or name is already registered.
+func (r *Registry) Register(name string, constructor Constructor) {
+	if constructor == nil {
+		panic("driver: Register constructor is nil")
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.constructors[name]; ok {
+		panic("driver: Register called twice for " + name)
+	}
+	if r.constructors == nil {
+		r.constructors = make(map[string]Constructor)
+	}
+	r.constructors[name] = constructor
+}
+
+// Open the driver registered under name.
+func (r *Registry) Open(name string, cfg Config) (Driver, error) {
+	r.mtx.RLock()
+	constructor, ok := r.constructors[name]
+	r.mtx.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("driver.Open: %w: %s", ErrUnknownDriver, name)
+	}
+	if cfg.Context == nil {
+		cfg.Context = context.Background()
+	}
+
+	d, err := constructor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("driver.Open %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// Names returns the sorted names of the registered drivers.
+func (r *Registry) Names() []string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	names := make([]string, 0, len(r.constructors))
+	for name := range r.constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Drivers is the default Registry, where drivers register themselves on init.
+var Drivers = new(Registry)
+
+// Register the constructor under name in the default Registry.
+func Register(name string, constructor Constructor) {
+	Drivers.Register(name, constructor)
+}
+
+// Open the driver registered under name in the default Registry.
+func Open(name string, cfg Config) (Driver, error) {
+	return Drivers.Open(name, cfg)
+}
@@ -0,0 +1,110 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeDriver struct {
+	cfg Config
+}
+
+func (*fakeDriver) SubscribeClosingPrices(string, string, ClosingPriceHandler) error { return nil }
+func (*fakeDriver) UnsubscribeClosingPrices(string, string) error                    { return nil }
+
+func fakeConstructor(cfg Config) (Driver, error) {
+	if cfg.Options["fail"] != "" {
+		return nil, errors.New(cfg.Options["fail"])
+	}
+	return &fakeDriver{cfg}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	var r Registry
+	r.Register("fake", fakeConstructor)
+	r.Register("another", fakeConstructor)
+
+	if got, want := r.Names(), []string{"another", "fake"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Registry.Names() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name    string
+		driver  string
+		cfg     Config
+		wantErr bool
+	}{
+		{"unknown", "foo", Config{}, true},
+		{"constructor error", "fake", Config{Options: map[string]string{"fail": "oops"}}, true},
+		{"success", "fake", Config{Options: map[string]string{"region": "us"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Open(tt.driver, tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Registry.Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			fd, ok := got.(*fakeDriver)
+			if !ok {
+				t.Fatalf("Registry.Open() = %T, want *fakeDriver", got)
+			}
+			if fd.cfg.Context == nil {
+				t.Error("Registry.Open() did not set a default Context")
+			}
+			if fd.cfg.Options["region"] != "us" {
+				t.Errorf("Registry.Open() options = %v", fd.cfg.Options)
+			}
+		})
+	}
+
+	if _, err := r.Open("foo", Config{}); !errors.Is(err, ErrUnknownDriver) {
+		t.Errorf("Registry.Open() error = %v, want %v", err, ErrUnknownDriver)
+	}
+}
+
+func TestRegistry_Register_panic(t *testing.T) {
+	tests := []struct {
+		name        string
+		constructor Constructor
+	}{
+		{"nil", nil},
+		{"twice", fakeConstructor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r Registry
+			r.Register("twice", fakeConstructor)
+
+			defer func() {
+				if recover() == nil {
+					t.Error("Registry.Register() did not panic")
+				}
+			}()
+
+			r.Register(tt.name, tt.constructor)
+		})
+	}
+}
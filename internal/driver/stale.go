@@ -0,0 +1,82 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"context"
+	"time"
+)
+
+// TimedHandler is an optional JSONHandler variant,
+// which also receives the time a message was read from the connection.
+type TimedHandler interface {
+	JSONHandler
+	TimedEvent(received time.Time, data []byte)
+}
+
+type receivedKey struct{}
+
+// WithReceived returns a child context carrying the receive time of a message.
+func WithReceived(ctx context.Context, received time.Time) context.Context {
+	return context.WithValue(ctx, receivedKey{}, received)
+}
+
+// Received returns the receive time of a message, if ctx carries one.
+func Received(ctx context.Context) (time.Time, bool) {
+	received, ok := ctx.Value(receivedKey{}).(time.Time)
+	return received, ok
+}
+
+// TimedEvent calls h.TimedEvent if h is a TimedHandler and ctx
+// carries a receive time, h.Event otherwise.
+func TimedEvent(ctx context.Context, h JSONHandler, data []byte) {
+	if th, ok := h.(TimedHandler); ok {
+		if received, ok := Received(ctx); ok {
+			th.TimedEvent(received, data)
+			return
+		}
+	}
+	h.Event(data)
+}
+
+// DropStale returns a handler which drops messages
+// received more than maxAge ago by the time they are delivered.
+// Messages without a receive time are always delivered.
+func DropStale(inner JSONHandler, maxAge time.Duration) TimedHandler {
+	return staleFilter{inner, maxAge}
+}
+
+type staleFilter struct {
+	h      JSONHandler
+	maxAge time.Duration
+}
+
+func (f staleFilter) Event(data []byte) { f.h.Event(data) }
+func (f staleFilter) Done()             { f.h.Done() }
+
+func (f staleFilter) TimedEvent(received time.Time, data []byte) {
+	if time.Since(received) > f.maxAge {
+		return
+	}
+	if th, ok := f.h.(TimedHandler); ok {
+		th.TimedEvent(received, data)
+		return
+	}
+	f.h.Event(data)
+}
@@ -0,0 +1,60 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package driver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDropStale(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	rec := new(recordHandler)
+	h := ContextAdapter(DropStale(rec, 2*delay))
+
+	old := WithReceived(context.Background(), time.Now())
+	time.Sleep(3 * delay) // artificial processing backlog
+
+	h.Event(old, []byte("old"))
+	h.Event(WithReceived(context.Background(), time.Now()), []byte("fresh"))
+	h.Event(context.Background(), []byte("untimed"))
+	h.Done(context.Background())
+
+	events, done := rec.get()
+	if want := []string{"fresh", "untimed"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("DropStale() events = %v, want %v", events, want)
+	}
+	if !done {
+		t.Error("DropStale() Done not forwarded")
+	}
+}
+
+func TestReceived(t *testing.T) {
+	if _, ok := Received(context.Background()); ok {
+		t.Error("Received() ok without receive time")
+	}
+
+	want := time.Unix(1, 0)
+	if got, ok := Received(WithReceived(context.Background(), want)); !ok || !got.Equal(want) {
+		t.Errorf("Received() = %v, %v, want %v", got, ok, want)
+	}
+}
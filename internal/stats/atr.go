@@ -0,0 +1,113 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// ATR is the Average True Range, a measure of volatility.
+// The true range of a period is the largest of:
+//
+//	high - low
+//	|high - previous close|
+//	|low - previous close|
+//
+// The average is seeded with the simple average of the first period
+// true ranges, after which Wilder's smoothing is applied,
+// like RSI.
+type ATR struct {
+	period int
+	n      int     // true ranges seen, up to period
+	value  float64 // average true range, or the sum while seeding
+}
+
+// NewATR returns an ATR over period true ranges.
+// It panics if period is not positive.
+func NewATR(period int) *ATR {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return &ATR{period: period}
+}
+
+// Move the ATR with the high and low of a closed period,
+// and the close of the period before it.
+// On the first Move there is no previous close,
+// so prevClose is ignored and the true range is high - low.
+func (a *ATR) Move(high, low, prevClose float64) {
+	tr := high - low
+	if a.n > 0 {
+		tr = math.Max(tr, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+	}
+
+	p := float64(a.period)
+
+	switch {
+	case a.n < a.period-1:
+		a.value += tr
+		a.n++
+	case a.n == a.period-1:
+		a.value = (a.value + tr) / p
+		a.n++
+	default:
+		a.value = (a.value*(p-1) + tr) / p
+	}
+}
+
+// Ready reports if period true ranges have been moved in.
+func (a *ATR) Ready() bool {
+	return a.n >= a.period
+}
+
+// Value returns the average true range, or 0 until Ready.
+func (a *ATR) Value() float64 {
+	if !a.Ready() {
+		return 0
+	}
+	return a.value
+}
+
+type atrJSON struct {
+	Period int     `json:"period"`
+	N      int     `json:"n"`
+	Value  float64 `json:"value"`
+}
+
+// MarshalJSON encodes the period and running average,
+// so that they can be restored with UnmarshalJSON.
+func (a ATR) MarshalJSON() ([]byte, error) {
+	return json.Marshal(atrJSON{a.period, a.n, a.value})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (a *ATR) UnmarshalJSON(data []byte) error {
+	var v atrJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.Period <= 0 || v.N < 0 || v.N > v.Period {
+		return errInvalidState
+	}
+
+	*a = ATR{v.Period, v.N, v.Value}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNewATR_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewATR did not panic")
+		}
+	}()
+	NewATR(0)
+}
+
+func TestATR_Value(t *testing.T) {
+	type bar struct{ high, low, close float64 }
+	bars := []bar{
+		{10, 8, 9},   // first: 10-8 = 2
+		{11, 9, 10},  // max(2, |11-9|, |9-9|) = 2
+		{12, 9, 11},  // max(3, |12-10|, |9-10|) = 3; seed 7/3
+		{15, 12, 14}, // max(3, |15-11|, |12-11|) = 4; (7/3*2+4)/3 = 26/9
+		{13, 10, 11}, // max(3, |13-14|, |10-14|) = 4; (26/9*2+4)/3 = 88/27
+	}
+
+	tests := []struct {
+		name  string
+		bars  int
+		want  float64
+		ready bool
+	}{
+		{"empty", 0, 0, false},
+		{"not ready", 2, 0, false},
+		{"seeded", 3, 7.0 / 3, true},
+		{"smoothed", 4, 26.0 / 9, true},
+		{"gap", 5, 88.0 / 27, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewATR(3)
+			// The first previous close must be ignored.
+			prevClose := 1000.0
+			for _, b := range bars[:tt.bars] {
+				a.Move(b.high, b.low, prevClose)
+				prevClose = b.close
+			}
+
+			if got := a.Ready(); got != tt.ready {
+				t.Errorf("ATR.Ready() = %v, want %v", got, tt.ready)
+			}
+			if got := a.Value(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ATR.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestATR_JSON(t *testing.T) {
+	a := NewATR(2)
+	a.Move(10, 8, 0)
+	a.Move(11, 9, 9)
+	a.Move(12, 9, 10)
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got ATR
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, a) {
+		t.Errorf("ATR JSON = %v, want %v", got, a)
+	}
+
+	if err = json.Unmarshal([]byte(`{"period":0}`), &got); err != errInvalidState {
+		t.Errorf("ATR.UnmarshalJSON() err = %v, want %v", err, errInvalidState)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+// Beta is the rolling beta of an asset against the market over a window:
+// cov(asset, market) / var(market).
+// Each Move is O(1), as running sums are kept.
+type Beta struct {
+	w pairWindow // x is market, y is asset
+}
+
+// NewBeta returns a Beta over window pairs of returns.
+// It panics if window is less than 2.
+func NewBeta(window int) *Beta {
+	return &Beta{w: newPairWindow(window)}
+}
+
+// Move the window by one pair of returns.
+func (b *Beta) Move(assetReturn, marketReturn float64) {
+	b.w.move(marketReturn, assetReturn)
+}
+
+// MoveAll moves all pairs of returns in, oldest first, and returns the resulting Value.
+// It is equivalent to calling Move for each pair, apart from rounding.
+// It panics if the slices differ in length.
+func (b *Beta) MoveAll(assetReturns, marketReturns []float64) float64 {
+	b.w.moveAll(marketReturns, assetReturns)
+	return b.Value()
+}
+
+// MarshalJSON encodes the window and running sums,
+// so that they can be restored with UnmarshalJSON.
+func (b Beta) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.w)
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (b *Beta) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &b.w)
+}
+
+// Value returns the beta.
+// When the market returns have no variance beta is undefined,
+// in which case 0 is returned.
+func (b *Beta) Value() float64 {
+	sxy, sxx, _ := b.w.sums()
+	if sxx == 0 {
+		return 0
+	}
+
+	return sxy / sxx
+}
@@ -0,0 +1,145 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestBeta_Value(t *testing.T) {
+	market := []float64{0.01, -0.02, 0.015, 0.03, -0.01, 0.005}
+
+	tests := []struct {
+		name   string
+		asset  []float64
+		market []float64
+		want   float64
+	}{
+		{
+			"empty",
+			nil,
+			nil,
+			0,
+		},
+		{
+			"double",
+			[]float64{0.02, -0.04, 0.03, 0.06, -0.02, 0.01},
+			market,
+			2,
+		},
+		{
+			"uncorrelated",
+			[]float64{0.01, 0.01, -0.01, 0.01, -0.01, -0.01},
+			[]float64{0.01, 0.02, 0.03, 0.03, 0.02, 0.01},
+			0,
+		},
+		{
+			"zero market variance",
+			[]float64{0.01, 0.02, 0.03},
+			[]float64{0.01, 0.01, 0.01},
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBeta(6)
+			for i := range tt.asset {
+				b.Move(tt.asset[i], tt.market[i])
+			}
+
+			if got := b.Value(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Beta.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeta_MoveAll(t *testing.T) {
+	for _, n := range []int{0, 5, 20, 1000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			asset, market := testSeries(n, 0.3), testSeries(n, 0.5)
+
+			want := NewBeta(20)
+			for i := range asset {
+				want.Move(asset[i], market[i])
+			}
+
+			if got := NewBeta(20).MoveAll(asset, market); math.Abs(got-want.Value()) > 1e-9 {
+				t.Errorf("Beta.MoveAll() = %v, want %v", got, want.Value())
+			}
+		})
+	}
+}
+
+func TestBeta_JSON(t *testing.T) {
+	b := NewBeta(10)
+	b.MoveAll(testSeries(15, 0.3), testSeries(15, 0.5))
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// non-addressable values must not lose state
+	value, err := json.Marshal(map[string]interface{}{"v": *b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"v":` + string(data) + `}`; string(value) != want {
+		t.Errorf("json.Marshal(value) = %s, want %s", value, want)
+	}
+
+	var got Beta
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	asset, market := testSeries(12, 0.2), testSeries(12, 0.9)
+	for i := range asset {
+		b.Move(asset[i], market[i])
+		got.Move(asset[i], market[i])
+
+		if got.Value() != b.Value() {
+			t.Fatalf("restored Beta.Value() = %v, want %v", got.Value(), b.Value())
+		}
+	}
+}
+
+func TestBeta_drift(t *testing.T) {
+	const window = 20
+	assets, market := priceSeries(100000, 0.53, 2000), priceSeries(100000, 0.37, 30000)
+	b := NewBeta(window)
+
+	for i := range assets {
+		b.Move(assets[i], market[i])
+		if i+1 < window {
+			continue
+		}
+
+		cxy, cxx, _ := naiveCovariances(market[i+1-window:i+1], assets[i+1-window:i+1])
+		want := cxy / cxx
+
+		if got := b.Value(); math.Abs(got-want) > 1e-6 {
+			t.Fatalf("Beta.Value() after %d moves = %v, want %v", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+var _ MovingIndicator = (*Bollinger)(nil)
+
+// Bollinger Bands around the moving average of a window:
+// k sample standard deviations above and below it.
+type Bollinger struct {
+	sd MovingStdDev
+	k  float64
+}
+
+// NewBollinger returns Bollinger Bands over window prices,
+// k standard deviations wide; commonly 20 and 2.
+// It panics if window is not positive.
+func NewBollinger(window int, k float64) *Bollinger {
+	return &Bollinger{sd: *NewMovingStdDev(window), k: k}
+}
+
+// Move the window by one price, updating all bands.
+func (b *Bollinger) Move(price float64) {
+	b.sd.Move(price)
+}
+
+// MoveAll moves all prices in, oldest first.
+func (b *Bollinger) MoveAll(prices []float64) {
+	b.sd.MoveAll(prices)
+}
+
+// IsFull reports if window prices have been moved in.
+func (b *Bollinger) IsFull() bool {
+	return b.sd.IsFull()
+}
+
+// Middle returns the moving average.
+func (b *Bollinger) Middle() float64 {
+	return b.sd.Mean()
+}
+
+// Upper returns the moving average plus k standard deviations.
+func (b *Bollinger) Upper() float64 {
+	return b.sd.Mean() + b.k*b.sd.StdDev()
+}
+
+// Lower returns the moving average minus k standard deviations.
+func (b *Bollinger) Lower() float64 {
+	return b.sd.Mean() - b.k*b.sd.StdDev()
+}
+
+// Value returns Middle, implementing MovingIndicator.
+func (b *Bollinger) Value() float64 { return b.Middle() }
+
+type bollingerJSON struct {
+	Window MovingStdDev `json:"window"`
+	K      float64      `json:"k"`
+}
+
+// MarshalJSON encodes the window and k, so that they can be restored with UnmarshalJSON.
+func (b Bollinger) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bollingerJSON{b.sd, b.k})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (b *Bollinger) UnmarshalJSON(data []byte) error {
+	var v bollingerJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*b = Bollinger{v.Window, v.K}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBollinger(t *testing.T) {
+	tests := []struct {
+		name                 string
+		window               int
+		k                    float64
+		prices               []float64
+		lower, middle, upper float64
+	}{
+		{"empty", 3, 2, nil, 0, 0, 0},
+		{"equal", 4, 2, []float64{1, 9, 5, 5, 5, 5}, 5, 5, 5},
+		// mean 4, sample stddev 2
+		{"spread", 3, 2, []float64{100, 2, 4, 6}, 0, 4, 8},
+		{"k 1", 3, 1, []float64{2, 4, 6}, 2, 4, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBollinger(tt.window, tt.k)
+			for _, p := range tt.prices {
+				b.Move(p)
+			}
+
+			if got := b.Lower(); math.Abs(got-tt.lower) > 1e-9 {
+				t.Errorf("Bollinger.Lower() = %v, want %v", got, tt.lower)
+			}
+			if got := b.Middle(); math.Abs(got-tt.middle) > 1e-9 {
+				t.Errorf("Bollinger.Middle() = %v, want %v", got, tt.middle)
+			}
+			if got := b.Upper(); math.Abs(got-tt.upper) > 1e-9 {
+				t.Errorf("Bollinger.Upper() = %v, want %v", got, tt.upper)
+			}
+		})
+	}
+}
+
+func TestBollinger_JSON(t *testing.T) {
+	b := NewBollinger(5, 2)
+	b.MoveAll(testSeries(8, 0.4))
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Bollinger
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, b) {
+		t.Errorf("Bollinger JSON = %v, want %v", got, b)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+)
+
+type pair struct {
+	x, y float64
+}
+
+func (p pair) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]float64{p.x, p.y})
+}
+
+func (p *pair) UnmarshalJSON(data []byte) error {
+	var v [2]float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	p.x, p.y = v[0], v[1]
+	return nil
+}
+
+// pairWindow keeps running sums over a moving window of value pairs,
+// so that (co)variances can be calculated in O(1).
+// The sums are of the values minus a shift, the means at the last resum.
+// Without it, n*xx - x*x cancels catastrophically for values like prices,
+// which vary little relative to their level.
+type pairWindow struct {
+	list movingList[pair]
+
+	shift            pair
+	x, y, xy, xx, yy float64
+}
+
+type pairWindowJSON struct {
+	Window movingList[pair] `json:"window"`
+	Shift  pair             `json:"shift"`
+	X      float64          `json:"x"`
+	Y      float64          `json:"y"`
+	XY     float64          `json:"xy"`
+	XX     float64          `json:"xx"`
+	YY     float64          `json:"yy"`
+}
+
+func (w pairWindow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pairWindowJSON{w.list, w.shift, w.x, w.y, w.xy, w.xx, w.yy})
+}
+
+func (w *pairWindow) UnmarshalJSON(data []byte) error {
+	var v pairWindowJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if len(v.Window.entries) < 2 {
+		return errInvalidState
+	}
+
+	*w = pairWindow{v.Window, v.Shift, v.X, v.Y, v.XY, v.XX, v.YY}
+	return nil
+}
+
+func newPairWindow(window int) pairWindow {
+	if window < 2 {
+		panic("stats: window must be at least 2")
+	}
+
+	return pairWindow{list: makeMovingList[pair](window)}
+}
+
+func (w *pairWindow) move(x, y float64) {
+	if w.list.count == 0 {
+		// Shift by the first pair until the window is full.
+		w.shift = pair{x, y}
+	}
+
+	full := w.list.full()
+	old := w.list.move(pair{x, y})
+
+	if full {
+		w.add(old, -1)
+	}
+	w.add(pair{x, y}, 1)
+
+	if w.list.pos == 0 {
+		w.resum()
+	}
+}
+
+// add the shifted pair to the sums, with sign -1 to remove it.
+func (w *pairWindow) add(p pair, sign float64) {
+	dx, dy := p.x-w.shift.x, p.y-w.shift.y
+
+	w.x += sign * dx
+	w.y += sign * dy
+	w.xy += sign * dx * dy
+	w.xx += sign * dx * dx
+	w.yy += sign * dy * dy
+}
+
+// resum shifts by the means of the inserted pairs
+// and recalculates the sums.
+func (w *pairWindow) resum() {
+	pairs := w.list.ordered()
+
+	w.shift = pair{}
+	for _, p := range pairs {
+		w.shift.x += p.x
+		w.shift.y += p.y
+	}
+	if n := float64(len(pairs)); n > 0 {
+		w.shift.x /= n
+		w.shift.y /= n
+	}
+
+	w.x, w.y, w.xy, w.xx, w.yy = 0, 0, 0, 0, 0
+	for _, p := range pairs {
+		w.add(p, 1)
+	}
+}
+
+// moveAll is equivalent to calling move for each pair of xs and ys.
+// When the window is filled by the slices, the running sums are rebuilt
+// from the window, instead of adding and subtracting every pair.
+// This may differ in rounding from repeated moves.
+// It panics if xs and ys differ in length.
+func (w *pairWindow) moveAll(xs, ys []float64) {
+	if len(xs) != len(ys) {
+		panic("stats: slices of unequal length")
+	}
+
+	if len(xs) < len(w.list.entries) {
+		for i := range xs {
+			w.move(xs[i], ys[i])
+		}
+		return
+	}
+
+	pairs := make([]pair, len(xs))
+	for i := range xs {
+		pairs[i] = pair{xs[i], ys[i]}
+	}
+	w.list.moveAll(pairs)
+	w.resum()
+}
+
+// relEpsilon is the relative size below which
+// a (co)variance is considered to be rounding noise.
+const relEpsilon = 1e-12
+
+// sums returns n² times the covariance of x and y,
+// and the variances of x and y.
+// Variances which are zero or rounding noise are returned as 0.
+func (w *pairWindow) sums() (sxy, sxx, syy float64) {
+	n := float64(w.list.count)
+
+	sxy = n*w.xy - w.x*w.y
+	sxx = n*w.xx - w.x*w.x
+	syy = n*w.yy - w.y*w.y
+
+	if sxx <= relEpsilon*n*w.xx {
+		sxx = 0
+	}
+	if syy <= relEpsilon*n*w.yy {
+		syy = 0
+	}
+
+	return sxy, sxx, syy
+}
+
+// Correlation is the rolling Pearson correlation coefficient
+// of two series over a window.
+// Each Move is O(1), as running sums are kept.
+type Correlation struct {
+	w pairWindow
+}
+
+// NewCorrelation returns a Correlation over window pairs of values.
+// It panics if window is less than 2.
+func NewCorrelation(window int) *Correlation {
+	return &Correlation{w: newPairWindow(window)}
+}
+
+// Move the window by one pair of values.
+func (c *Correlation) Move(x, y float64) {
+	c.w.move(x, y)
+}
+
+// MoveAll moves all pairs of xs and ys in, oldest first, and returns the resulting Value.
+// It is equivalent to calling Move for each pair, apart from rounding.
+// It panics if xs and ys differ in length.
+func (c *Correlation) MoveAll(xs, ys []float64) float64 {
+	c.w.moveAll(xs, ys)
+	return c.Value()
+}
+
+// MarshalJSON encodes the window and running sums,
+// so that they can be restored with UnmarshalJSON.
+func (c Correlation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.w)
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (c *Correlation) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &c.w)
+}
+
+// Value returns the correlation coefficient in the range [-1, 1].
+// When either series has no variance, for example less than 2 values
+// or all values equal, the correlation is undefined and 0 is returned.
+func (c *Correlation) Value() float64 {
+	sxy, sxx, syy := c.w.sums()
+	if sxx == 0 || syy == 0 {
+		return 0
+	}
+
+	return math.Max(-1, math.Min(1, sxy/math.Sqrt(sxx*syy)))
+}
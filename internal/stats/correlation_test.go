@@ -0,0 +1,240 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestCorrelation_Value(t *testing.T) {
+	tests := []struct {
+		name   string
+		window int
+		x, y   []float64
+		want   float64
+	}{
+		{
+			"empty",
+			3,
+			nil,
+			nil,
+			0,
+		},
+		{
+			"correlated",
+			4,
+			[]float64{1, 2, 3, 4},
+			[]float64{3, 5, 7, 9},
+			1,
+		},
+		{
+			"anti-correlated",
+			4,
+			[]float64{1, 2, 3, 4},
+			[]float64{-1, -3, -5, -7},
+			-1,
+		},
+		{
+			"uncorrelated",
+			4,
+			[]float64{1, 2, 3, 4},
+			[]float64{1, -1, -1, 1},
+			0,
+		},
+		{
+			"zero variance",
+			4,
+			[]float64{1, 2, 3, 4},
+			[]float64{5, 5, 5, 5},
+			0,
+		},
+		{
+			"evicted",
+			3,
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{9, 0, 1, 2, 3},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCorrelation(tt.window)
+			for i := range tt.x {
+				c.Move(tt.x[i], tt.y[i])
+			}
+
+			if got := c.Value(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Correlation.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCorrelation_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewCorrelation() did not panic")
+		}
+	}()
+
+	NewCorrelation(1)
+}
+
+func TestCorrelation_MoveAll(t *testing.T) {
+	for _, n := range []int{0, 5, 20, 1000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			xs, ys := testSeries(n, 0.3), testSeries(n, 0.5)
+
+			want := NewCorrelation(20)
+			for i := range xs {
+				want.Move(xs[i], ys[i])
+			}
+
+			if got := NewCorrelation(20).MoveAll(xs, ys); math.Abs(got-want.Value()) > 1e-9 {
+				t.Errorf("Correlation.MoveAll() = %v, want %v", got, want.Value())
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Correlation.MoveAll() did not panic on unequal lengths")
+		}
+	}()
+	NewCorrelation(3).MoveAll([]float64{1}, nil)
+}
+
+func TestCorrelation_JSON(t *testing.T) {
+	c := NewCorrelation(10)
+	c.MoveAll(testSeries(15, 0.3), testSeries(15, 0.5))
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// non-addressable values must not lose state
+	value, err := json.Marshal(map[string]interface{}{"v": *c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"v":` + string(data) + `}`; string(value) != want {
+		t.Errorf("json.Marshal(value) = %s, want %s", value, want)
+	}
+
+	var got Correlation
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	xs, ys := testSeries(12, 0.2), testSeries(12, 0.9)
+	for i := range xs {
+		c.Move(xs[i], ys[i])
+		got.Move(xs[i], ys[i])
+
+		if got.Value() != c.Value() {
+			t.Fatalf("restored Correlation.Value() = %v, want %v", got.Value(), c.Value())
+		}
+	}
+
+	if err = json.Unmarshal([]byte(`{"window":{"entries":[[1,2]]}}`), &got); !errors.Is(err, errInvalidState) {
+		t.Errorf("Correlation.UnmarshalJSON() error = %v, want %v", err, errInvalidState)
+	}
+}
+
+// priceSeries returns n pseudo random prices which trend up from level,
+// and move by at most 1 around the trend.
+// They cancel badly in sums of squares.
+func priceSeries(n int, seed, level float64) []float64 {
+	values := testSeries(n, seed)
+	for i := range values {
+		values[i] = level + float64(i)/10 + values[i]/100
+	}
+	return values
+}
+
+// naiveCovariances returns the covariance of xs and ys and their variances,
+// calculated in two passes.
+func naiveCovariances(xs, ys []float64) (cxy, cxx, cyy float64) {
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx /= float64(len(xs))
+	my /= float64(len(ys))
+
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		cxy += dx * dy
+		cxx += dx * dx
+		cyy += dy * dy
+	}
+	return cxy, cxx, cyy
+}
+
+func TestPairWindow_sums_drift(t *testing.T) {
+	const window = 20
+	xs, ys := priceSeries(100000, 0.37, 30000), priceSeries(100000, 0.53, 2000)
+	w := newPairWindow(window)
+
+	for i := range xs {
+		w.move(xs[i], ys[i])
+
+		var x, y, xy, xx, yy float64
+		for _, p := range w.list.ordered() {
+			dx, dy := p.x-w.shift.x, p.y-w.shift.y
+			x += dx
+			y += dy
+			xy += dx * dy
+			xx += dx * dx
+			yy += dy * dy
+		}
+
+		for _, s := range [][2]float64{{w.x, x}, {w.y, y}, {w.xy, xy}, {w.xx, xx}, {w.yy, yy}} {
+			if math.Abs(s[0]-s[1]) > 1e-9 {
+				t.Fatalf("pairWindow sum after %d moves = %v, want %v", i+1, s[0], s[1])
+			}
+		}
+	}
+}
+
+func TestCorrelation_drift(t *testing.T) {
+	const window = 20
+	xs, ys := priceSeries(100000, 0.37, 30000), priceSeries(100000, 0.53, 2000)
+	c := NewCorrelation(window)
+
+	for i := range xs {
+		c.Move(xs[i], ys[i])
+		if i+1 < window {
+			continue
+		}
+
+		cxy, cxx, cyy := naiveCovariances(xs[i+1-window:i+1], ys[i+1-window:i+1])
+		want := cxy / math.Sqrt(cxx*cyy)
+
+		if got := c.Value(); math.IsNaN(got) || math.Abs(got-want) > 1e-6 {
+			t.Fatalf("Correlation.Value() after %d moves = %v, want %v", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+// Drawdown tracks the decline of an equity curve from its running peak.
+// It works on the cumulative equity series, not on a window.
+// The zero value is ready to use.
+type Drawdown struct {
+	peak    float64
+	current float64
+	max     float64
+}
+
+// Move adds the next equity value.
+func (d *Drawdown) Move(equity float64) {
+	if equity > d.peak {
+		d.peak = equity
+	}
+
+	d.current = 0
+	if d.peak > 0 {
+		d.current = (d.peak - equity) / d.peak * 100
+	}
+
+	if d.current > d.max {
+		d.max = d.current
+	}
+}
+
+// MoveAll adds all equity values in order and returns the resulting Current drawdown.
+// It is equivalent to calling Move for each value.
+func (d *Drawdown) MoveAll(equity []float64) float64 {
+	for _, e := range equity {
+		d.Move(e)
+	}
+	return d.current
+}
+
+type drawdownJSON struct {
+	Peak    float64 `json:"peak"`
+	Current float64 `json:"current"`
+	Max     float64 `json:"max"`
+}
+
+// MarshalJSON encodes the state, so that it can be restored with UnmarshalJSON.
+func (d Drawdown) MarshalJSON() ([]byte, error) {
+	return json.Marshal(drawdownJSON{d.peak, d.current, d.max})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (d *Drawdown) UnmarshalJSON(data []byte) error {
+	var v drawdownJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*d = Drawdown{v.Peak, v.Current, v.Max}
+	return nil
+}
+
+// Current returns the percentage the last equity value is below the peak.
+func (d *Drawdown) Current() float64 {
+	return d.current
+}
+
+// Max returns the worst drawdown seen, in percent.
+func (d *Drawdown) Max() float64 {
+	return d.max
+}
@@ -0,0 +1,95 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestDrawdown(t *testing.T) {
+	tests := []struct {
+		equity      float64
+		wantCurrent float64
+		wantMax     float64
+	}{
+		{100, 0, 0},
+		{110, 0, 0},
+		{99, 10, 10},
+		{88, 20, 20},
+		{104.5, 5, 20},
+		{120, 0, 20},
+		{108, 10, 20},
+	}
+
+	var d Drawdown
+
+	for _, tt := range tests {
+		d.Move(tt.equity)
+
+		if got := d.Current(); math.Abs(got-tt.wantCurrent) > 1e-9 {
+			t.Errorf("Drawdown.Current() after %v = %v, want %v", tt.equity, got, tt.wantCurrent)
+		}
+		if got := d.Max(); math.Abs(got-tt.wantMax) > 1e-9 {
+			t.Errorf("Drawdown.Max() after %v = %v, want %v", tt.equity, got, tt.wantMax)
+		}
+	}
+}
+
+func TestDrawdown_MoveAll(t *testing.T) {
+	equity := testSeries(100, 0.2)
+
+	var want Drawdown
+	for _, e := range equity {
+		want.Move(e)
+	}
+
+	var got Drawdown
+	if current := got.MoveAll(equity); current != want.Current() {
+		t.Errorf("Drawdown.MoveAll() = %v, want %v", current, want.Current())
+	}
+	if got != want {
+		t.Errorf("Drawdown.MoveAll() = %v, want %v", got, want)
+	}
+}
+
+func TestDrawdown_JSON(t *testing.T) {
+	var d Drawdown
+	d.MoveAll([]float64{100, 120, 90, 110})
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Drawdown
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []float64{80, 130, 125} {
+		d.Move(e)
+		got.Move(e)
+
+		if got != d {
+			t.Fatalf("restored Drawdown = %v, want %v", got, d)
+		}
+	}
+}
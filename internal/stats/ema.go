@@ -0,0 +1,108 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+var _ MovingIndicator = (*EMA)(nil)
+
+// EMA is an exponential moving average, which weights
+// recent values more heavily than older ones:
+//
+//	ema = alpha*value + (1-alpha)*ema
+//
+// with the smoothing factor alpha = 2/(period+1).
+type EMA struct {
+	alpha  float64
+	value  float64
+	seeded bool
+}
+
+// NewEMA returns an unseeded EMA over period values.
+// The first value moved in seeds the EMA.
+// It panics if period is not positive.
+func NewEMA(period int) *EMA {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return &EMA{alpha: 2 / float64(period+1)}
+}
+
+// NewEMASeeded returns an EMA seeded with seed,
+// usually the simple average of the first period values.
+// It panics if period is not positive.
+func NewEMASeeded(period int, seed float64) *EMA {
+	e := NewEMA(period)
+	e.value, e.seeded = seed, true
+	return e
+}
+
+// Move the EMA with the value of a closed period.
+func (e *EMA) Move(value float64) {
+	if !e.seeded {
+		e.value, e.seeded = value, true
+		return
+	}
+
+	e.value = e.alpha*value + (1-e.alpha)*e.value
+}
+
+// Value returns the current EMA.
+// An unseeded EMA, which did not receive any value yet, returns 0.
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+// AvgIncl returns the EMA as if value was moved in,
+// which can be weighed for partial periods.
+// The EMA is not modified, which makes it suitable
+// for the live value of a period that has not closed yet.
+// Weight 1.0 equals the value after Move, lower weights scale alpha down.
+// An unseeded EMA returns value.
+func (e *EMA) AvgIncl(value, weight float64) float64 {
+	if !e.seeded {
+		return value
+	}
+
+	alpha := e.alpha * weight
+	return alpha*value + (1-alpha)*e.value
+}
+
+type emaJSON struct {
+	Alpha  float64 `json:"alpha"`
+	Value  float64 `json:"value"`
+	Seeded bool    `json:"seeded"`
+}
+
+// MarshalJSON encodes the smoothing factor and current value,
+// so that they can be restored with UnmarshalJSON.
+func (e EMA) MarshalJSON() ([]byte, error) {
+	return json.Marshal(emaJSON{e.alpha, e.value, e.seeded})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (e *EMA) UnmarshalJSON(data []byte) error {
+	var v emaJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*e = EMA{v.Alpha, v.Value, v.Seeded}
+	return nil
+}
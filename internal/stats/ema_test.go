@@ -0,0 +1,141 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestEMA_Value(t *testing.T) {
+	// period 3, alpha 0.5
+	tests := []struct {
+		name   string
+		ema    *EMA
+		values []float64
+		want   float64
+	}{
+		{
+			"unseeded",
+			NewEMA(3),
+			nil,
+			0,
+		},
+		{
+			"seed",
+			NewEMA(3),
+			[]float64{2},
+			2,
+		},
+		{
+			"moves",
+			NewEMA(3),
+			[]float64{2, 4, 8},
+			0.5*8 + 0.5*(0.5*4+0.5*2),
+		},
+		{
+			"sma seed",
+			NewEMASeeded(3, 3),
+			[]float64{4, 8},
+			0.5*8 + 0.5*(0.5*4+0.5*3),
+		},
+		{
+			"period 1 follows value",
+			NewEMA(1),
+			[]float64{2, 4, 8},
+			8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, v := range tt.values {
+				tt.ema.Move(v)
+			}
+
+			if got := tt.ema.Value(); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("EMA.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEMA_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewEMA(0) did not panic")
+		}
+	}()
+	NewEMA(0)
+}
+
+func TestEMA_JSON(t *testing.T) {
+	e := NewEMA(9)
+	e.Move(1)
+	e.Move(2)
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got EMA
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != *e {
+		t.Errorf("EMA round trip = %+v, want %+v", got, *e)
+	}
+}
+
+func TestEMA_AvgIncl(t *testing.T) {
+	tests := []struct {
+		name   string
+		ema    *EMA
+		value  float64
+		weight float64
+		want   float64
+	}{
+		{"unseeded", NewEMA(3), 4, 0.5, 4},
+		{"full weight", NewEMASeeded(3, 2), 4, 1, 3},
+		{"half weight", NewEMASeeded(3, 2), 4, 0.5, 2.5},
+		{"zero weight", NewEMASeeded(3, 2), 4, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := *tt.ema
+			if got := tt.ema.AvgIncl(tt.value, tt.weight); got != tt.want {
+				t.Errorf("EMA.AvgIncl() = %v, want %v", got, tt.want)
+			}
+			if *tt.ema != before {
+				t.Errorf("EMA.AvgIncl() modified the EMA")
+			}
+		})
+	}
+}
+
+func ExampleEMA_AvgIncl() {
+	e := NewEMASeeded(3, 2.0)
+	fmt.Println(e.AvgIncl(4.0, 1.0))
+	fmt.Println(e.AvgIncl(4.0, 0.5))
+
+	// Output: 3
+	// 2.5
+}
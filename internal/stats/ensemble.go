@@ -0,0 +1,71 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+type ensembleMember struct {
+	ind    MovingIndicator
+	weight float64
+}
+
+// MAEnsemble is a weighted combination of moving indicators,
+// for example 0.5*EMA(12) + 0.3*SMA(26) + 0.2*WMA(9).
+// Weights are normalized, so they do not need to add up to 1.
+type MAEnsemble struct {
+	members []ensembleMember
+}
+
+// NewMAEnsemble returns an empty MAEnsemble.
+func NewMAEnsemble() *MAEnsemble {
+	return &MAEnsemble{}
+}
+
+// Add ind to the ensemble with weight and return the ensemble.
+// It panics if weight is negative.
+func (e *MAEnsemble) Add(ind MovingIndicator, weight float64) *MAEnsemble {
+	if weight < 0 {
+		panic("stats: weight must not be negative")
+	}
+
+	e.members = append(e.members, ensembleMember{ind, weight})
+	return e
+}
+
+// Move value into all members.
+func (e *MAEnsemble) Move(value float64) {
+	for _, m := range e.members {
+		m.ind.Move(value)
+	}
+}
+
+// Value returns the weight-normalized combination of the member values.
+// Value returns 0 when the total weight is 0.
+func (e *MAEnsemble) Value() float64 {
+	var sum, weights float64
+
+	for _, m := range e.members {
+		sum += m.weight * m.ind.Value()
+		weights += m.weight
+	}
+
+	if weights == 0 {
+		return 0
+	}
+
+	return sum / weights
+}
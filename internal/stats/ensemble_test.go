@@ -0,0 +1,65 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMAEnsemble(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []float64
+		want    float64
+	}{
+		{"empty", nil, 0},
+		{"zero weights", []float64{0, 0}, 0},
+		{"normalized", []float64{1, 1}, 4},
+		{"weighted", []float64{0.75, 0.25}, 4.25},
+		{"unnormalized", []float64{3, 1}, 4.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewMAEnsemble()
+			for i, w := range tt.weights {
+				e.Add(NewMovingAverage(2+i*2), w) // MA(2), MA(4)
+			}
+
+			for _, v := range []float64{1, 2, 3, 4, 5} {
+				e.Move(v)
+			}
+
+			// MA(2) = 4.5, MA(4) = 3.5
+			if got := e.Value(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("MAEnsemble.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMAEnsemble_Add_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MAEnsemble.Add() did not panic")
+		}
+	}()
+
+	NewMAEnsemble().Add(NewMovingAverage(2), -1)
+}
@@ -0,0 +1,113 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// DefaultLambda is the RiskMetrics decay factor for daily returns.
+const DefaultLambda = 0.94
+
+var _ MovingIndicator = (*EWVariance)(nil)
+
+// EWVariance estimates volatility with an exponentially weighted
+// moving variance, as in RiskMetrics:
+//
+//	var = λ*var + (1-λ)*ret²
+//
+// The first return seeds the mean and variance.
+type EWVariance struct {
+	lambda   float64
+	n        int
+	mean     float64
+	variance float64
+}
+
+// NewEWVariance returns an estimator with decay factor lambda,
+// which must be in the open interval (0, 1).
+// Zero uses DefaultLambda.
+func NewEWVariance(lambda float64) *EWVariance {
+	if lambda == 0 {
+		lambda = DefaultLambda
+	}
+	if lambda < 0 || lambda >= 1 {
+		panic("stats: lambda must be between 0 and 1")
+	}
+	return &EWVariance{lambda: lambda}
+}
+
+// Move adds the return of one period.
+func (e *EWVariance) Move(ret float64) {
+	e.n++
+	if e.n == 1 {
+		e.mean = ret
+		e.variance = ret * ret
+		return
+	}
+
+	e.mean = e.lambda*e.mean + (1-e.lambda)*ret
+	e.variance = e.lambda*e.variance + (1-e.lambda)*ret*ret
+}
+
+// Mean returns the exponentially weighted mean return.
+func (e *EWVariance) Mean() float64 {
+	return e.mean
+}
+
+// Variance returns the exponentially weighted variance.
+// Like RiskMetrics it assumes a zero mean return.
+func (e *EWVariance) Variance() float64 {
+	return e.variance
+}
+
+// Volatility returns the square root of Variance.
+func (e *EWVariance) Volatility() float64 {
+	return math.Sqrt(e.variance)
+}
+
+// Value returns Volatility, so that EWVariance implements MovingIndicator.
+func (e *EWVariance) Value() float64 {
+	return e.Volatility()
+}
+
+type ewVarianceJSON struct {
+	Lambda   float64 `json:"lambda"`
+	N        int     `json:"n"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+}
+
+// MarshalJSON encodes the decay factor and current estimates,
+// so that they can be restored with UnmarshalJSON.
+func (e EWVariance) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ewVarianceJSON{e.lambda, e.n, e.mean, e.variance})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (e *EWVariance) UnmarshalJSON(data []byte) error {
+	var v ewVarianceJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*e = EWVariance{v.Lambda, v.N, v.Mean, v.Variance}
+	return nil
+}
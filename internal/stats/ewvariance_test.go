@@ -0,0 +1,127 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+var _ MovingIndicator = &EWVariance{}
+
+func TestEWVariance(t *testing.T) {
+	returns := []float64{0.01, -0.02, 0.015}
+
+	tests := []struct {
+		name     string
+		returns  []float64
+		mean     float64
+		variance float64
+	}{
+		{
+			"empty",
+			nil,
+			0,
+			0,
+		},
+		{
+			"seed",
+			returns[:1],
+			0.01,
+			0.0001,
+		},
+		{
+			// 0.94*0.0001 + 0.06*0.0004
+			"two",
+			returns[:2],
+			0.0082,
+			0.000118,
+		},
+		{
+			// 0.94*0.000118 + 0.06*0.000225
+			"three",
+			returns,
+			0.008608,
+			0.00012442,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEWVariance(DefaultLambda)
+			for _, r := range tt.returns {
+				e.Move(r)
+			}
+
+			if got := e.Mean(); math.Abs(got-tt.mean) > 1e-12 {
+				t.Errorf("EWVariance.Mean() = %v, want %v", got, tt.mean)
+			}
+			if got := e.Variance(); math.Abs(got-tt.variance) > 1e-12 {
+				t.Errorf("EWVariance.Variance() = %v, want %v", got, tt.variance)
+			}
+			if got, want := e.Volatility(), math.Sqrt(tt.variance); math.Abs(got-want) > 1e-12 {
+				t.Errorf("EWVariance.Volatility() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNewEWVariance_panic(t *testing.T) {
+	for _, lambda := range []float64{1, -0.5, 1.5} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewEWVariance(%v) did not panic", lambda)
+				}
+			}()
+			NewEWVariance(lambda)
+		}()
+	}
+}
+
+func TestNewEWVariance_default(t *testing.T) {
+	if got := NewEWVariance(0).lambda; got != DefaultLambda {
+		t.Errorf("NewEWVariance(0) lambda = %v, want %v", got, DefaultLambda)
+	}
+}
+
+func TestEWVariance_JSON(t *testing.T) {
+	e := NewEWVariance(DefaultLambda)
+	e.Move(0.01)
+	e.Move(-0.02)
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got EWVariance
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != *e {
+		t.Errorf("EWVariance round trip = %+v, want %+v", got, *e)
+	}
+
+	e.Move(0.015)
+	got.Move(0.015)
+	if got != *e {
+		t.Errorf("EWVariance after Move = %+v, want %+v", got, *e)
+	}
+}
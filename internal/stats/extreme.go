@@ -0,0 +1,185 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+type extremeEntry struct {
+	seq   int
+	value float64
+}
+
+// movingExtreme keeps a monotonic deque of the window's values,
+// so that the extreme is always in front and Move is amortized O(1).
+// The window itself is kept in list, for persistence.
+type movingExtreme struct {
+	list  movingList[float64]
+	deque []extremeEntry
+	seq   int // sequence number of the next value
+}
+
+func newMovingExtreme(period int) movingExtreme {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return movingExtreme{list: makeMovingList[float64](period)}
+}
+
+// move the window by one value. An entry is kept in front of value
+// as long as keep(entry, value) is true.
+func (m *movingExtreme) move(value float64, keep func(a, b float64) bool) {
+	n := len(m.list.entries)
+	if n == 0 {
+		return
+	}
+	m.list.move(value)
+	m.push(value, keep)
+}
+
+func (m *movingExtreme) push(value float64, keep func(a, b float64) bool) {
+	n := len(m.list.entries)
+
+	for len(m.deque) > 0 && m.deque[0].seq <= m.seq-n {
+		m.deque = m.deque[1:]
+	}
+	for len(m.deque) > 0 && !keep(m.deque[len(m.deque)-1].value, value) {
+		m.deque = m.deque[:len(m.deque)-1]
+	}
+
+	m.deque = append(m.deque, extremeEntry{m.seq, value})
+	m.seq++
+}
+
+// rebuild the deque from the window.
+func (m *movingExtreme) rebuild(keep func(a, b float64) bool) {
+	entries := m.list.entries
+	n := len(entries)
+
+	m.deque, m.seq = m.deque[:0], 0
+	for i := m.list.count; i > 0; i-- {
+		m.push(entries[(m.list.pos-i+n)%n], keep)
+	}
+}
+
+func (m movingExtreme) value() float64 {
+	if len(m.deque) == 0 {
+		return 0
+	}
+	return m.deque[0].value
+}
+
+func less(a, b float64) bool    { return a < b }
+func greater(a, b float64) bool { return a > b }
+
+var (
+	_ MovingIndicator = (*MovingMin)(nil)
+	_ MovingIndicator = (*MovingMax)(nil)
+)
+
+// MovingMin is the lowest value over a window, such as the lowest low
+// of the last N klines. Move is amortized O(1), Min is O(1).
+type MovingMin struct {
+	w movingExtreme
+}
+
+// NewMovingMin returns an empty MovingMin over period values.
+// It panics if period is not positive.
+func NewMovingMin(period int) *MovingMin {
+	return &MovingMin{w: newMovingExtreme(period)}
+}
+
+// Move the window by one value, replacing the oldest.
+func (m *MovingMin) Move(value float64) { m.w.move(value, less) }
+
+// MoveAll moves all values in, oldest first, and returns the resulting Min.
+func (m *MovingMin) MoveAll(values []float64) float64 {
+	m.w.list.moveAll(values)
+	m.w.rebuild(less)
+	return m.Min()
+}
+
+// IsFull reports if period values have been moved in.
+func (m MovingMin) IsFull() bool { return m.w.list.full() }
+
+// Min returns the lowest value in the window, or 0 when it is empty.
+func (m MovingMin) Min() float64 { return m.w.value() }
+
+// Value returns Min, implementing MovingIndicator.
+func (m MovingMin) Value() float64 { return m.Min() }
+
+// MarshalJSON encodes the window, so that it can be restored with UnmarshalJSON.
+func (m MovingMin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.w.list)
+}
+
+// UnmarshalJSON restores a window encoded by MarshalJSON.
+func (m *MovingMin) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.w.list); err != nil {
+		return err
+	}
+
+	m.w.rebuild(less)
+	return nil
+}
+
+// MovingMax is the highest value over a window, such as the highest high
+// of the last N klines. Move is amortized O(1), Max is O(1).
+type MovingMax struct {
+	w movingExtreme
+}
+
+// NewMovingMax returns an empty MovingMax over period values.
+// It panics if period is not positive.
+func NewMovingMax(period int) *MovingMax {
+	return &MovingMax{w: newMovingExtreme(period)}
+}
+
+// Move the window by one value, replacing the oldest.
+func (m *MovingMax) Move(value float64) { m.w.move(value, greater) }
+
+// MoveAll moves all values in, oldest first, and returns the resulting Max.
+func (m *MovingMax) MoveAll(values []float64) float64 {
+	m.w.list.moveAll(values)
+	m.w.rebuild(greater)
+	return m.Max()
+}
+
+// IsFull reports if period values have been moved in.
+func (m MovingMax) IsFull() bool { return m.w.list.full() }
+
+// Max returns the highest value in the window, or 0 when it is empty.
+func (m MovingMax) Max() float64 { return m.w.value() }
+
+// Value returns Max, implementing MovingIndicator.
+func (m MovingMax) Value() float64 { return m.Max() }
+
+// MarshalJSON encodes the window, so that it can be restored with UnmarshalJSON.
+func (m MovingMax) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.w.list)
+}
+
+// UnmarshalJSON restores a window encoded by MarshalJSON.
+func (m *MovingMax) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.w.list); err != nil {
+		return err
+	}
+
+	m.w.rebuild(greater)
+	return nil
+}
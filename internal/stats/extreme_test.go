@@ -0,0 +1,137 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestMovingMinMax(t *testing.T) {
+	tests := []struct {
+		name     string
+		period   int
+		values   []float64
+		min, max float64
+	}{
+		{"empty", 3, nil, 0, 0},
+		{"single", 3, []float64{4}, 4, 4},
+		{"partial", 3, []float64{4, 1}, 1, 4},
+		{"full", 3, []float64{4, 1, 7}, 1, 7},
+		{"evict max", 3, []float64{9, 1, 5, 3}, 1, 5},
+		{"evict min", 3, []float64{1, 9, 5, 3}, 3, 9},
+		{"evict both", 2, []float64{1, 9, 5, 6}, 5, 6},
+		{"duplicates", 3, []float64{5, 5, 2, 5, 5}, 2, 5},
+		{"duplicate evicted", 2, []float64{5, 5, 3}, 3, 5},
+		{"period 1", 1, []float64{3, 8, 2}, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lo, hi := NewMovingMin(tt.period), NewMovingMax(tt.period)
+			for _, v := range tt.values {
+				lo.Move(v)
+				hi.Move(v)
+			}
+			if got := lo.Min(); got != tt.min {
+				t.Errorf("MovingMin.Min() = %v, want %v", got, tt.min)
+			}
+			if got := hi.Max(); got != tt.max {
+				t.Errorf("MovingMax.Max() = %v, want %v", got, tt.max)
+			}
+
+			lo, hi = NewMovingMin(tt.period), NewMovingMax(tt.period)
+			if got := lo.MoveAll(tt.values); got != tt.min {
+				t.Errorf("MovingMin.MoveAll() = %v, want %v", got, tt.min)
+			}
+			if got := hi.MoveAll(tt.values); got != tt.max {
+				t.Errorf("MovingMax.MoveAll() = %v, want %v", got, tt.max)
+			}
+		})
+	}
+}
+
+func TestMovingMinMax_naive(t *testing.T) {
+	for _, period := range []int{1, 3, 10} {
+		t.Run(strconv.Itoa(period), func(t *testing.T) {
+			values := testSeries(300, 1.3)
+			lo, hi := NewMovingMin(period), NewMovingMax(period)
+
+			for i, v := range values {
+				lo.Move(v)
+				hi.Move(v)
+
+				start := i + 1 - period
+				if start < 0 {
+					start = 0
+				}
+				wantMin, wantMax := values[start], values[start]
+				for _, w := range values[start : i+1] {
+					if w < wantMin {
+						wantMin = w
+					}
+					if w > wantMax {
+						wantMax = w
+					}
+				}
+
+				if got := lo.Min(); got != wantMin {
+					t.Fatalf("move %d: MovingMin.Min() = %v, want %v", i, got, wantMin)
+				}
+				if got := hi.Max(); got != wantMax {
+					t.Fatalf("move %d: MovingMax.Max() = %v, want %v", i, got, wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestMovingMinMax_JSON(t *testing.T) {
+	values := []float64{9, 1, 5, 3}
+	lo, hi := NewMovingMin(3), NewMovingMax(3)
+	lo.MoveAll(values)
+	hi.MoveAll(values)
+
+	data, err := json.Marshal(lo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotMin MovingMin
+	if err = json.Unmarshal(data, &gotMin); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = json.Marshal(hi); err != nil {
+		t.Fatal(err)
+	}
+	var gotMax MovingMax
+	if err = json.Unmarshal(data, &gotMax); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restored state keeps evicting correctly.
+	gotMin.Move(4)
+	gotMax.Move(4)
+	if got := gotMin.Min(); got != 3 {
+		t.Errorf("MovingMin.Min() = %v, want 3", got)
+	}
+	if got := gotMax.Max(); got != 5 {
+		t.Errorf("MovingMax.Max() = %v, want 5", got)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+var _ MovingIndicator = (*MACD)(nil)
+
+// MACD is the Moving Average Convergence Divergence indicator.
+// The MACD line is the difference between a fast and a slow EMA of the price,
+// the signal line is an EMA of the MACD line.
+//
+// All EMAs are seeded by the first value they receive,
+// so the lines are only meaningful after about slow+signal prices.
+type MACD struct {
+	fast, slow, signal EMA
+}
+
+// NewMACD returns a MACD with the fast, slow and signal periods,
+// commonly 12, 26 and 9.
+// It panics if a period is not positive or fast is not less than slow.
+func NewMACD(fast, slow, signal int) *MACD {
+	if fast >= slow {
+		panic("stats: fast period must be less than slow period")
+	}
+	return &MACD{
+		fast:   *NewEMA(fast),
+		slow:   *NewEMA(slow),
+		signal: *NewEMA(signal),
+	}
+}
+
+// Move the MACD with the closing price of a period.
+func (m *MACD) Move(price float64) {
+	m.fast.Move(price)
+	m.slow.Move(price)
+	m.signal.Move(m.MACD())
+}
+
+// MACD returns the MACD line: fast EMA - slow EMA.
+func (m *MACD) MACD() float64 {
+	return m.fast.Value() - m.slow.Value()
+}
+
+// Signal returns the signal line, the EMA of the MACD line.
+func (m *MACD) Signal() float64 {
+	return m.signal.Value()
+}
+
+// Histogram returns MACD - Signal.
+// It crosses zero when the MACD line crosses the signal line.
+func (m *MACD) Histogram() float64 {
+	return m.MACD() - m.Signal()
+}
+
+// Value returns the MACD line, implementing MovingIndicator.
+func (m *MACD) Value() float64 { return m.MACD() }
+
+type macdJSON struct {
+	Fast   EMA `json:"fast"`
+	Slow   EMA `json:"slow"`
+	Signal EMA `json:"signal"`
+}
+
+// MarshalJSON encodes the EMAs, so that they can be restored with UnmarshalJSON.
+func (m MACD) MarshalJSON() ([]byte, error) {
+	return json.Marshal(macdJSON{m.fast, m.slow, m.signal})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (m *MACD) UnmarshalJSON(data []byte) error {
+	var v macdJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*m = MACD{v.Fast, v.Slow, v.Signal}
+	return nil
+}
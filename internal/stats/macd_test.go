@@ -0,0 +1,113 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNewMACD_panic(t *testing.T) {
+	tests := []struct {
+		name               string
+		fast, slow, signal int
+	}{
+		{"fast not less", 26, 12, 9},
+		{"zero signal", 12, 26, 0},
+		{"zero fast", 0, 26, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("NewMACD did not panic")
+				}
+			}()
+			NewMACD(tt.fast, tt.slow, tt.signal)
+		})
+	}
+}
+
+func TestMACD_ramp(t *testing.T) {
+	m := NewMACD(3, 6, 4)
+	if m.MACD() != 0 || m.Signal() != 0 || m.Histogram() != 0 {
+		t.Fatal("MACD not zero before the first price")
+	}
+
+	// Flat prices have no divergence.
+	for i := 0; i < 10; i++ {
+		m.Move(10)
+	}
+	if got := m.MACD(); got != 0 {
+		t.Errorf("flat: MACD.MACD() = %v, want 0", got)
+	}
+
+	// Rising prices: the fast EMA leads, the signal line lags the MACD line.
+	price := 10.0
+	for i := 0; i < 20; i++ {
+		price++
+		m.Move(price)
+
+		if got := m.Histogram(); got != m.MACD()-m.Signal() {
+			t.Fatalf("MACD.Histogram() = %v, want %v", got, m.MACD()-m.Signal())
+		}
+	}
+	if m.MACD() <= 0 || m.Histogram() <= 0 {
+		t.Fatalf("rising: MACD %v, histogram %v, want positive", m.MACD(), m.Histogram())
+	}
+
+	// Falling prices: the MACD line crosses below the signal line first,
+	// then below zero.
+	var crossed int
+	for i := 1; i <= 20; i++ {
+		price--
+		m.Move(price)
+
+		if crossed == 0 && m.Histogram() < 0 {
+			crossed = i
+		}
+	}
+	if crossed == 0 || crossed > 3 {
+		t.Errorf("histogram crossed zero after %d falling prices, want 1 to 3", crossed)
+	}
+	if m.MACD() >= 0 || m.Signal() >= 0 {
+		t.Errorf("falling: MACD %v, signal %v, want negative", m.MACD(), m.Signal())
+	}
+}
+
+func TestMACD_JSON(t *testing.T) {
+	m := NewMACD(12, 26, 9)
+	for _, p := range testSeries(50, 0.2) {
+		m.Move(p)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got MACD
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, m) {
+		t.Errorf("MACD JSON = %v, want %v", got, m)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+var _ MovingIndicator = (*MovingMedian)(nil)
+
+// MovingMedian is the rolling median over a fixed window of values.
+// Next to the window a sorted copy is kept, so that Move costs
+// a binary search and a shift, and Median is O(1).
+type MovingMedian struct {
+	list   movingList[float64]
+	sorted []float64 // values in the window, ascending
+}
+
+// NewMovingMedian returns an empty MovingMedian over period values.
+// It panics if period is not positive.
+func NewMovingMedian(period int) *MovingMedian {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return &MovingMedian{
+		list:   makeMovingList[float64](period),
+		sorted: make([]float64, 0, period),
+	}
+}
+
+// Move the window by one value, replacing the oldest.
+func (mm *MovingMedian) Move(value float64) {
+	if len(mm.list.entries) == 0 {
+		return
+	}
+
+	full := mm.list.full()
+	old := mm.list.move(value)
+
+	if full {
+		i := sort.SearchFloat64s(mm.sorted, old)
+		mm.sorted = append(mm.sorted[:i], mm.sorted[i+1:]...)
+	}
+
+	i := sort.SearchFloat64s(mm.sorted, value)
+	mm.sorted = append(mm.sorted, 0)
+	copy(mm.sorted[i+1:], mm.sorted[i:])
+	mm.sorted[i] = value
+}
+
+// MoveAll moves all values in, oldest first, and returns the resulting Median.
+func (mm *MovingMedian) MoveAll(values []float64) float64 {
+	mm.list.moveAll(values)
+	mm.resort()
+	return mm.Median()
+}
+
+// resort rebuilds the sorted copy from the window.
+func (mm *MovingMedian) resort() {
+	mm.sorted = append(mm.sorted[:0], mm.list.ordered()...)
+	sort.Float64s(mm.sorted)
+}
+
+// MarshalJSON encodes the window, so that it can be restored with UnmarshalJSON.
+func (mm MovingMedian) MarshalJSON() ([]byte, error) {
+	return json.Marshal(mm.list)
+}
+
+// UnmarshalJSON restores a window encoded by MarshalJSON.
+func (mm *MovingMedian) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &mm.list); err != nil {
+		return err
+	}
+
+	mm.sorted = make([]float64, 0, len(mm.list.entries))
+	mm.resort()
+	return nil
+}
+
+// Values returns a copy of the values in the window, oldest first.
+func (mm MovingMedian) Values() []float64 {
+	return mm.list.ordered()
+}
+
+// IsFull reports if period values have been moved in.
+func (mm MovingMedian) IsFull() bool {
+	return mm.list.full()
+}
+
+// Median returns the median of the values moved in so far.
+// For an even number of values it is the average of the two middle values.
+// Median returns 0 when there are no values.
+func (mm MovingMedian) Median() float64 {
+	n := len(mm.sorted)
+	switch {
+	case n == 0:
+		return 0
+	case n%2 == 1:
+		return mm.sorted[n/2]
+	default:
+		return (mm.sorted[n/2-1] + mm.sorted[n/2]) / 2
+	}
+}
+
+// Value returns Median, implementing MovingIndicator.
+func (mm MovingMedian) Value() float64 { return mm.Median() }
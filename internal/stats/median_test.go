@@ -0,0 +1,120 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestNewMovingMedian_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewMovingMedian did not panic")
+		}
+	}()
+	NewMovingMedian(0)
+}
+
+func TestMovingMedian_Median(t *testing.T) {
+	tests := []struct {
+		name   string
+		period int
+		values []float64
+		want   float64
+	}{
+		{"empty", 3, nil, 0},
+		{"single", 3, []float64{4}, 4},
+		{"partial even", 3, []float64{5, 1}, 3},
+		{"odd", 3, []float64{9, 1, 5}, 5},
+		{"odd moved", 3, []float64{9, 1, 5, 0, 2}, 2},
+		{"even", 4, []float64{8, 2, 6, 4}, 5},
+		{"even moved", 4, []float64{8, 2, 6, 4, 1, 7}, 5},
+		{"duplicates", 4, []float64{3, 3, 1, 3, 3}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mm := NewMovingMedian(tt.period)
+			for _, v := range tt.values {
+				mm.Move(v)
+			}
+			if got := mm.Median(); got != tt.want {
+				t.Errorf("MovingMedian.Median() = %v, want %v", got, tt.want)
+			}
+
+			mm = NewMovingMedian(tt.period)
+			if got := mm.MoveAll(tt.values); got != tt.want {
+				t.Errorf("MovingMedian.MoveAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func naiveMedian(values []float64) float64 {
+	s := append([]float64(nil), values...)
+	sort.Float64s(s)
+	if n := len(s); n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[len(s)/2-1] + s[len(s)/2]) / 2
+}
+
+func TestMovingMedian_naive(t *testing.T) {
+	for _, period := range []int{1, 2, 7, 10} {
+		t.Run(strconv.Itoa(period), func(t *testing.T) {
+			values := testSeries(200, 0.9)
+			mm := NewMovingMedian(period)
+
+			for i, v := range values {
+				mm.Move(v)
+
+				start := i + 1 - period
+				if start < 0 {
+					start = 0
+				}
+				if got, want := mm.Median(), naiveMedian(values[start:i+1]); got != want {
+					t.Fatalf("move %d: MovingMedian.Median() = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMovingMedian_JSON(t *testing.T) {
+	mm := NewMovingMedian(4)
+	for _, v := range []float64{8, 2, 6, 4, 1} {
+		mm.Move(v)
+	}
+
+	data, err := json.Marshal(mm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got MovingMedian
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, mm) {
+		t.Errorf("MovingMedian JSON = %v, want %v", got, mm)
+	}
+}
@@ -20,56 +20,329 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 // trading algoritms.
 package stats
 
+import (
+	"encoding/json"
+	"errors"
+)
+
 // movingList of values, not save for concurrent use.
 type movingList[T any] struct {
 	entries []T
 	pos     int
+	count   int // inserted values, up to len(entries)
 }
 
+// newMovingList returns a list which is filled with entries.
 func newMovingList[T any](entries []T) movingList[T] {
-	return movingList[T]{entries: entries}
+	return movingList[T]{entries: entries, count: len(entries)}
+}
+
+// makeMovingList returns an empty list with room for size entries.
+func makeMovingList[T any](size int) movingList[T] {
+	return movingList[T]{entries: make([]T, size)}
 }
 
-// move replaces the oldest value in the list.
-func (l *movingList[T]) move(v T) {
+// move replaces the oldest value in the list,
+// and returns the replaced value.
+func (l *movingList[T]) move(v T) (old T) {
 	if len(l.entries) == 0 {
-		return
+		return old
 	}
 
+	old = l.entries[l.pos]
 	l.entries[l.pos] = v
 
+	if l.count < len(l.entries) {
+		l.count++
+	}
+
 	if l.pos++; l.pos >= len(l.entries) {
 		l.pos = 0
 	}
+
+	return old
+}
+
+// moveAll is equivalent to calling move for each value,
+// but values which would be replaced again are skipped.
+func (l *movingList[T]) moveAll(values []T) {
+	n := len(l.entries)
+	if n == 0 {
+		return
+	}
+
+	if skip := len(values) - n; skip > 0 {
+		l.pos = (l.pos + skip) % n
+		values = values[skip:]
+	}
+
+	for _, v := range values {
+		l.move(v)
+	}
+}
+
+// ordered returns a copy of the inserted values, oldest first.
+func (l movingList[T]) ordered() []T {
+	n := len(l.entries)
+	values := make([]T, 0, l.count)
+
+	for i := l.count; i > 0; i-- {
+		values = append(values, l.entries[(l.pos-i+n)%n])
+	}
+
+	return values
+}
+
+// resize the list to size entries, keeping the newest inserted values.
+func (l *movingList[T]) resize(size int) {
+	values := l.ordered()
+	if len(values) > size {
+		values = values[len(values)-size:]
+	}
+
+	l.entries = make([]T, size)
+	l.count = copy(l.entries, values)
+	l.pos = l.count % size
+}
+
+// full reports if the list holds as many inserted values as it has entries.
+func (l movingList[T]) full() bool {
+	return l.count == len(l.entries)
+}
+
+type movingListJSON[T any] struct {
+	Entries []T `json:"entries"`
+	Pos     int `json:"pos"`
+	Count   int `json:"count"`
+}
+
+func (l movingList[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(movingListJSON[T]{l.entries, l.pos, l.count})
+}
+
+var errInvalidState = errors.New("stats: invalid state")
+
+// list validates and returns the decoded list.
+func (v movingListJSON[T]) list() (movingList[T], error) {
+	if v.Pos < 0 || v.Count < 0 || v.Count > len(v.Entries) || (v.Pos > 0 && v.Pos >= len(v.Entries)) {
+		return movingList[T]{}, errInvalidState
+	}
+
+	return movingList[T]{entries: v.Entries, pos: v.Pos, count: v.Count}, nil
+}
+
+func (l *movingList[T]) UnmarshalJSON(data []byte) error {
+	var v movingListJSON[T]
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	list, err := v.list()
+	if err != nil {
+		return err
+	}
+
+	*l = list
+	return nil
+}
+
+// MovingIndicator is a value calculated over a moving window of values.
+type MovingIndicator interface {
+	Move(value float64)
+	Value() float64
 }
 
+var _ MovingIndicator = (*MovingAverage)(nil)
+
 type MovingAverage struct {
-	list movingList[float64]
+	list  movingList[float64]
+	total float64 // running sum of the entries
+}
+
+// NewMovingAverage returns an empty MovingAverage over period values.
+// Until period values are moved in, averages are calculated
+// over the values moved in so far.
+// It panics if period is not positive.
+func NewMovingAverage(period int) *MovingAverage {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return &MovingAverage{list: makeMovingList[float64](period)}
+}
+
+// NewMovingAverageFrom returns a MovingAverage with a window
+// pre-filled with a copy of values, oldest first.
+// The period is the length of values.
+func NewMovingAverageFrom(values []float64) *MovingAverage {
+	ma := &MovingAverage{list: newMovingList(append([]float64(nil), values...))}
+	ma.resum()
+	return ma
+}
+
+// Len returns the window size, the period of the MovingAverage.
+func (ma MovingAverage) Len() int {
+	return len(ma.list.entries)
+}
+
+// Resize the window to period values, keeping the newest values.
+// When the window grows, it is not full until enough new values are moved in.
+// It panics if period is not positive.
+func (ma *MovingAverage) Resize(period int) {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+
+	ma.list.resize(period)
+	ma.resum()
+}
+
+// Values returns a copy of the values in the window, oldest first.
+func (ma MovingAverage) Values() []float64 {
+	return ma.list.ordered()
+}
+
+// Count returns the number of values moved in, up to Len.
+func (ma MovingAverage) Count() int {
+	return ma.list.count
+}
+
+// IsFull reports if Len values have been moved in.
+// Until then, the average is over fewer values than the period.
+func (ma MovingAverage) IsFull() bool {
+	return ma.list.full()
+}
+
+type movingAverageJSON struct {
+	movingListJSON[float64]
+	Sum *float64 `json:"sum,omitempty"`
+}
+
+// MarshalJSON encodes the window and cached sum,
+// so that they can be restored exactly with UnmarshalJSON.
+func (ma MovingAverage) MarshalJSON() ([]byte, error) {
+	l := ma.list
+	return json.Marshal(movingAverageJSON{
+		movingListJSON[float64]{l.entries, l.pos, l.count},
+		&ma.total,
+	})
+}
+
+// UnmarshalJSON restores a window encoded by MarshalJSON.
+// Without a sum, as encoded before it was cached, the sum is recalculated.
+func (ma *MovingAverage) UnmarshalJSON(data []byte) error {
+	var v movingAverageJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	list, err := v.list()
+	if err != nil {
+		return err
+	}
+
+	ma.list = list
+	if v.Sum != nil {
+		ma.total = *v.Sum
+	} else {
+		ma.resum()
+	}
+	return nil
 }
 
 // Move the list of values by one position.
 // Removes the oldest and replaces it by the passed value.
+//
+// Only move in values of closed periods, such as closed klines.
+// A kline stream updates the open kline many times before it closes,
+// moving in each update would corrupt the average.
+// Use PeekIncl or AvgIncl to include the value of the open period.
 func (ma *MovingAverage) Move(value float64) {
-	ma.list.move(value)
+	if len(ma.list.entries) == 0 {
+		return
+	}
+
+	ma.total += value - ma.list.move(value)
+
+	// Rounding errors of the running sum accumulate,
+	// recalculating once per window keeps Move amortized O(1).
+	if ma.list.pos == 0 {
+		ma.resum()
+	}
+}
+
+// MoveAll moves all values in, oldest first, and returns the resulting Avg.
+// It is equivalent to calling Move for each value,
+// but values which would not end up in the window are skipped.
+func (ma *MovingAverage) MoveAll(values []float64) float64 {
+	ma.list.moveAll(values)
+	ma.resum()
+	return ma.Avg()
+}
+
+// sum returns the cached sum of the entries.
+func (ma MovingAverage) sum() float64 {
+	return ma.total
 }
 
-func (ma MovingAverage) sum() (sum float64) {
+// resum recalculates the cached sum from the entries.
+func (ma *MovingAverage) resum() {
+	ma.total = 0
 	for _, v := range ma.list.entries {
-		sum += v
+		ma.total += v
 	}
-
-	return sum
 }
 
 // Avg returns the current average of the MovingAverage slice.
+// Only values that have been moved in count,
+// so a window that is not yet full does not average in zeros.
+// Avg returns 0 when there are no values.
 func (ma MovingAverage) Avg() float64 {
-	return ma.sum() / float64(len(ma.list.entries))
+	if ma.list.count == 0 {
+		return 0
+	}
+
+	return ma.sum() / float64(ma.list.count)
 }
 
+// Value returns Avg, implementing MovingIndicator.
+func (ma MovingAverage) Value() float64 { return ma.Avg() }
+
 // AvgIncl calculates the current average with the addional value,
 // which can be weighed for partial blocks.
+// The window is not modified, which makes it suitable
+// for the live value of a period that has not closed yet.
 // Weight 1.0 will consider this value with the same weight as all values.
 // A lower weight will influence the resulting average less.
 func (ma MovingAverage) AvgIncl(value, weight float64) float64 {
-	return (value*weight + ma.sum()) / (float64(len(ma.list.entries)) + weight)
+	return (value*weight + ma.sum()) / (float64(ma.list.count) + weight)
+}
+
+// PeekIncl returns the average including the live value of the open period,
+// with the same weight as the closed values. It equals AvgIncl(value, 1).
+func (ma MovingAverage) PeekIncl(value float64) float64 {
+	return ma.AvgIncl(value, 1)
+}
+
+// WeightedAvg returns the average with geometrically decaying weights.
+// The newest value has weight 1, the value before it decay,
+// the one before that decay², and so on.
+// A decay of 1.0 equals Avg, lower values make the average more responsive.
+func (ma MovingAverage) WeightedAvg(decay float64) float64 {
+	entries := ma.list.entries
+	n := len(entries)
+
+	var sum, weights float64
+	w := 1.0
+
+	for i := 1; i <= ma.list.count; i++ {
+		sum += w * entries[(ma.list.pos-i+n)%n]
+		weights += w
+		w *= decay
+	}
+
+	if weights == 0 {
+		return 0
+	}
+
+	return sum / weights
 }
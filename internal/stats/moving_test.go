@@ -19,6 +19,8 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package stats
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
@@ -46,6 +48,7 @@ func Test_movingList_move(t *testing.T) {
 			movingList[int]{
 				entries: []int{3},
 				pos:     0,
+				count:   1,
 			},
 		},
 		{
@@ -55,6 +58,7 @@ func Test_movingList_move(t *testing.T) {
 			movingList[int]{
 				entries: []int{4, 2, 3},
 				pos:     1,
+				count:   3,
 			},
 		},
 		{
@@ -64,6 +68,17 @@ func Test_movingList_move(t *testing.T) {
 			movingList[int]{
 				entries: []int{7, 5, 6},
 				pos:     1,
+				count:   3,
+			},
+		},
+		{
+			"fill",
+			makeMovingList[int](3),
+			[]int{1, 2},
+			movingList[int]{
+				entries: []int{1, 2, 0},
+				pos:     2,
+				count:   2,
 			},
 		},
 	}
@@ -101,20 +116,94 @@ func BenchmarkList_Move(b *testing.B) {
 	}
 }
 
+func TestNewMovingAverage(t *testing.T) {
+	ma := NewMovingAverage(3)
+	if got := ma.Len(); got != 3 {
+		t.Fatalf("MovingAverage.Len() = %d, want 3", got)
+	}
+
+	for _, v := range []float64{1.0, 2.0, 3.0} {
+		ma.Move(v)
+	}
+	if got := ma.Avg(); got != 2.0 {
+		t.Errorf("MovingAverage.Avg() = %v, want 2", got)
+	}
+}
+
+func TestNewMovingAverage_panic(t *testing.T) {
+	for _, period := range []int{0, -1} {
+		t.Run(strconv.Itoa(period), func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != "stats: period must be positive" {
+					t.Errorf("NewMovingAverage() panic = %v", r)
+				}
+			}()
+
+			NewMovingAverage(period)
+		})
+	}
+}
+
+func TestNewMovingAverageFrom(t *testing.T) {
+	values := []float64{1.0, 2.0, 3.0}
+	ma := NewMovingAverageFrom(values)
+
+	if got := ma.Avg(); got != 2.0 {
+		t.Errorf("MovingAverage.Avg() = %v, want 2", got)
+	}
+
+	// Oldest value 1.0 is replaced.
+	ma.Move(4.0)
+	if got := ma.Avg(); got != 3.0 {
+		t.Errorf("MovingAverage.Avg() = %v, want 3", got)
+	}
+
+	if values[0] != 1.0 {
+		t.Error("NewMovingAverageFrom() did not copy values")
+	}
+	if got := ma.Len(); got != 3 {
+		t.Errorf("MovingAverage.Len() = %d, want 3", got)
+	}
+}
+
+func ExampleNewMovingAverage() {
+	ma := NewMovingAverage(2)
+	ma.Move(1.0)
+	ma.Move(2.0)
+	ma.Move(3.0)
+	fmt.Println(ma.Avg())
+
+	// Output: 2.5
+}
+
+func ExampleNewMovingAverageFrom() {
+	ma := NewMovingAverageFrom([]float64{1.0, 2.0, 3.0})
+	fmt.Println(ma.Avg())
+	ma.Move(4.0)
+	fmt.Println(ma.Avg())
+
+	// Output: 2
+	// 3
+}
+
 func TestMovingAverage_Move(t *testing.T) {
-	ma := MovingAverage{list: newMovingList([]float64{1.0, 2.0, 3.0})}
-	want := MovingAverage{list: movingList[float64]{
-		entries: []float64{4.0, 2.0, 3.0},
-		pos:     1,
-	}}
+	ma := NewMovingAverageFrom([]float64{1.0, 2.0, 3.0})
+	want := MovingAverage{
+		list: movingList[float64]{
+			entries: []float64{4.0, 2.0, 3.0},
+			pos:     1,
+			count:   3,
+		},
+		total: 9.0,
+	}
 
-	if ma.Move(4.0); !reflect.DeepEqual(ma, want) {
+	if ma.Move(4.0); !reflect.DeepEqual(*ma, want) {
 		t.Errorf("MovingAverage.Avg() =\n%v\nwant\n%v", ma, want)
 	}
 }
 
 func TestMovingAverage_sum(t *testing.T) {
-	ma := MovingAverage{list: newMovingList([]float64{1.0, 2.0, 3.0})}
+	ma := NewMovingAverageFrom([]float64{1.0, 2.0, 3.0})
 	const want = 6.0
 
 	if got := ma.sum(); got != want {
@@ -122,8 +211,38 @@ func TestMovingAverage_sum(t *testing.T) {
 	}
 }
 
+func TestMovingAverage_sum_drift(t *testing.T) {
+	ma := NewMovingAverage(7)
+	values := testSeries(10000, 0.37)
+
+	for i, v := range values {
+		ma.Move(v)
+
+		var want float64
+		for _, e := range ma.list.entries {
+			want += e
+		}
+		if got := ma.sum(); math.Abs(got-want) > 1e-9 {
+			t.Fatalf("MovingAverage.sum() after %d moves = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func BenchmarkMovingAverage_MoveAvg(b *testing.B) {
+	for _, bb := range benchListSizes {
+		ma := NewMovingAverage(bb)
+
+		b.Run(strconv.Itoa(bb), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ma.Move(float64(i))
+				ma.Avg()
+			}
+		})
+	}
+}
+
 func TestMovingAverage_Avg(t *testing.T) {
-	ma := MovingAverage{list: newMovingList([]float64{1.0, 2.0, 3.0})}
+	ma := NewMovingAverageFrom([]float64{1.0, 2.0, 3.0})
 	const want = 2.0
 
 	if got := ma.Avg(); got != want {
@@ -131,6 +250,31 @@ func TestMovingAverage_Avg(t *testing.T) {
 	}
 }
 
+func TestMovingAverage_warmup(t *testing.T) {
+	ma := NewMovingAverage(5)
+
+	if got := ma.Avg(); got != 0 {
+		t.Errorf("MovingAverage.Avg() empty = %v, want 0", got)
+	}
+	if got := ma.AvgIncl(4.0, 1.0); got != 4.0 {
+		t.Errorf("MovingAverage.AvgIncl() empty = %v, want 4", got)
+	}
+
+	ma.Move(1.0)
+	ma.Move(2.0)
+	ma.Move(6.0)
+
+	if got := ma.Avg(); got != 3.0 {
+		t.Errorf("MovingAverage.Avg() = %v, want 3", got)
+	}
+	if got := ma.AvgIncl(7.0, 1.0); got != 4.0 {
+		t.Errorf("MovingAverage.AvgIncl() = %v, want 4", got)
+	}
+	if got, want := ma.WeightedAvg(0.5), (6.0+2.0*0.5+1.0*0.25)/1.75; got != want {
+		t.Errorf("MovingAverage.WeightedAvg() = %v, want %v", got, want)
+	}
+}
+
 func BenchmarkTestMovingAverage_Avg(b *testing.B) {
 	for _, bb := range benchListSizes {
 		list := make([]float64, bb)
@@ -139,7 +283,7 @@ func BenchmarkTestMovingAverage_Avg(b *testing.B) {
 			list[i] = float64(i)
 		}
 
-		ma := MovingAverage{list: newMovingList(list)}
+		ma := NewMovingAverageFrom(list)
 
 		b.Run(strconv.Itoa(bb), func(b *testing.B) {
 			ma.Avg()
@@ -148,7 +292,7 @@ func BenchmarkTestMovingAverage_Avg(b *testing.B) {
 }
 
 func TestMovingAverage_AvgIncl(t *testing.T) {
-	ma := MovingAverage{list: newMovingList([]float64{1.0, 2.0, 3.0})}
+	ma := NewMovingAverageFrom([]float64{1.0, 2.0, 3.0})
 
 	tests := []struct {
 		v      float64
@@ -176,6 +320,46 @@ func TestMovingAverage_AvgIncl(t *testing.T) {
 	}
 }
 
+func TestMovingAverage_PeekIncl(t *testing.T) {
+	type update struct {
+		close  float64
+		closed bool
+	}
+
+	// Updates of a kline stream: the open kline changes until it closes.
+	updates := []update{
+		{1, true},
+		{2, true},
+		{5, false},
+		{4, false},
+		{3, true},
+		{6, false},
+	}
+	wantLive := []float64{1, 1.5, 8.0 / 3, 7.0 / 3, 2, 3}
+
+	ma := NewMovingAverage(3)
+	for i, u := range updates {
+		var live float64
+		if u.closed {
+			ma.Move(u.close)
+			live = ma.Avg()
+		} else {
+			live = ma.PeekIncl(u.close)
+		}
+
+		if math.Abs(live-wantLive[i]) > 1e-9 {
+			t.Errorf("update %d: live average = %v, want %v", i, live, wantLive[i])
+		}
+	}
+
+	if got := ma.Avg(); got != 2 {
+		t.Errorf("MovingAverage.Avg() = %v, want 2, open klines must not be moved in", got)
+	}
+	if got, want := ma.PeekIncl(6), ma.AvgIncl(6, 1); got != want {
+		t.Errorf("MovingAverage.PeekIncl() = %v, want %v", got, want)
+	}
+}
+
 func BenchmarkTestMovingAverage_AvgIncl(b *testing.B) {
 	for _, bb := range benchListSizes {
 		list := make([]float64, bb)
@@ -184,7 +368,7 @@ func BenchmarkTestMovingAverage_AvgIncl(b *testing.B) {
 			list[i] = float64(i)
 		}
 
-		ma := MovingAverage{list: newMovingList(list)}
+		ma := NewMovingAverageFrom(list)
 
 		b.Run(strconv.Itoa(bb), func(b *testing.B) {
 			ma.AvgIncl(4.0, 0.5)
@@ -192,11 +376,366 @@ func BenchmarkTestMovingAverage_AvgIncl(b *testing.B) {
 	}
 }
 
+func TestMovingAverage_WeightedAvg(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves []float64
+		decay float64
+		want  float64
+	}{
+		{
+			"decay 1 equals Avg",
+			nil,
+			1.0,
+			2.0,
+		},
+		{
+			"decay",
+			nil,
+			0.5,
+			(3.0 + 2.0*0.5 + 1.0*0.25) / 1.75,
+		},
+		{
+			"decay after move",
+			[]float64{4.0},
+			0.5,
+			(4.0 + 3.0*0.5 + 2.0*0.25) / 1.75,
+		},
+		{
+			"decay after wrap",
+			[]float64{4.0, 5.0, 6.0, 7.0},
+			0.5,
+			(7.0 + 6.0*0.5 + 5.0*0.25) / 1.75,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ma := NewMovingAverageFrom([]float64{1.0, 2.0, 3.0})
+			for _, v := range tt.moves {
+				ma.Move(v)
+			}
+
+			if got := ma.WeightedAvg(tt.decay); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("MovingAverage.WeightedAvg() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func ExampleMovingAverage_AvgIncl() {
-	ma := MovingAverage{list: newMovingList([]float64{1.0, 2.0, 3.0})}
+	ma := NewMovingAverageFrom([]float64{1.0, 2.0, 3.0})
 	fmt.Println(ma.AvgIncl(4.0, 1.0))
 	fmt.Println(ma.AvgIncl(4.0, 0.5))
 
 	// Output: 2.5
 	// 2.2857142857142856
 }
+
+// testSeries returns n pseudo random values.
+func testSeries(n int, seed float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.Sin(float64(i)*seed) * 100
+	}
+	return values
+}
+
+func TestMovingAverage_MoveAll(t *testing.T) {
+	tests := []struct {
+		period int
+		n      int
+	}{
+		{5, 0},
+		{5, 3},
+		{5, 5},
+		{5, 12},
+		{50, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.period, tt.n), func(t *testing.T) {
+			values := testSeries(tt.n, 0.7)
+
+			want := NewMovingAverage(tt.period)
+			want.Move(1)
+			for _, v := range values {
+				want.Move(v)
+			}
+
+			got := NewMovingAverage(tt.period)
+			got.Move(1)
+			// The cached sums may differ by rounding.
+			if avg := got.MoveAll(values); math.Abs(avg-want.Avg()) > 1e-9 {
+				t.Errorf("MovingAverage.MoveAll() = %v, want %v", avg, want.Avg())
+			}
+			if !reflect.DeepEqual(got.list, want.list) {
+				t.Errorf("MovingAverage.MoveAll() = %v, want %v", got.list, want.list)
+			}
+		})
+	}
+}
+
+func TestMovingAverage_JSON(t *testing.T) {
+	ma := NewMovingAverage(5)
+	ma.MoveAll(testSeries(7, 0.7))
+
+	data, err := json.Marshal(ma)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got MovingAverage
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range testSeries(8, 0.4) {
+		ma.Move(v)
+		got.Move(v)
+
+		if got.Avg() != ma.Avg() || got.WeightedAvg(0.5) != ma.WeightedAvg(0.5) {
+			t.Fatalf("restored MovingAverage = %v, want %v", got, ma)
+		}
+	}
+}
+
+func TestMovingAverage_JSON_sum(t *testing.T) {
+	ma := NewMovingAverage(7)
+	for _, v := range testSeries(10, 0.9) {
+		ma.Move(v)
+	}
+
+	data, err := json.Marshal(ma)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got MovingAverage
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, ma) {
+		t.Fatalf("restored MovingAverage = %v, want %v", got, ma)
+	}
+
+	// Oldest values are evicted first.
+	want := append(ma.Values()[3:], 100, 200, 300)
+	for _, v := range []float64{100, 200, 300} {
+		ma.Move(v)
+		got.Move(v)
+
+		if got.Avg() != ma.Avg() {
+			t.Fatalf("MovingAverage.Avg() = %v, want %v", got.Avg(), ma.Avg())
+		}
+	}
+	if !reflect.DeepEqual(got.Values(), want) {
+		t.Errorf("MovingAverage.Values() = %v, want %v", got.Values(), want)
+	}
+}
+
+func TestMovingAverage_UnmarshalJSON_noSum(t *testing.T) {
+	var ma MovingAverage
+	if err := json.Unmarshal([]byte(`{"entries":[1,2,3],"pos":1,"count":3}`), &ma); err != nil {
+		t.Fatal(err)
+	}
+	if got := ma.Avg(); got != 2 {
+		t.Errorf("MovingAverage.Avg() = %v, want 2", got)
+	}
+}
+
+func TestMovingAverage_UnmarshalJSON_invalid(t *testing.T) {
+	tests := []string{
+		`{"entries":[1,2],"pos":2,"count":2}`,
+		`{"entries":[1,2],"pos":0,"count":3}`,
+		`{"entries":[1,2],"pos":-1,"count":1}`,
+	}
+	for _, data := range tests {
+		var ma MovingAverage
+		if err := json.Unmarshal([]byte(data), &ma); !errors.Is(err, errInvalidState) {
+			t.Errorf("MovingAverage.UnmarshalJSON(%s) error = %v, want %v", data, err, errInvalidState)
+		}
+	}
+}
+
+func TestMovingAverage_IsFull(t *testing.T) {
+	for _, period := range []int{1, 3, 10} {
+		t.Run(strconv.Itoa(period), func(t *testing.T) {
+			ma := NewMovingAverage(period)
+			for i := 1; i <= period+2; i++ {
+				ma.Move(float64(i))
+
+				if got, want := ma.IsFull(), i >= period; got != want {
+					t.Fatalf("after %d moves: MovingAverage.IsFull() = %v, want %v", i, got, want)
+				}
+
+				want := i
+				if want > period {
+					want = period
+				}
+				if got := ma.Count(); got != want {
+					t.Fatalf("after %d moves: MovingAverage.Count() = %d, want %d", i, got, want)
+				}
+			}
+		})
+	}
+
+	if !NewMovingAverageFrom([]float64{1, 2}).IsFull() {
+		t.Error("NewMovingAverageFrom().IsFull() = false, want true")
+	}
+}
+
+func TestIsFull(t *testing.T) {
+	const period = 4
+
+	type fuller interface {
+		MovingIndicator
+		IsFull() bool
+	}
+	indicators := map[string]fuller{
+		"MovingAverage": NewMovingAverage(period),
+		"MovingStdDev":  NewMovingStdDev(period),
+		"WMA":           NewWMA(period),
+		"MovingMedian":  NewMovingMedian(period),
+		"MovingMin":     NewMovingMin(period),
+		"MovingMax":     NewMovingMax(period),
+		"Bollinger":     NewBollinger(period, 2),
+	}
+	for name, ind := range indicators {
+		t.Run(name, func(t *testing.T) {
+			for i := 1; i <= period+1; i++ {
+				ind.Move(float64(i))
+
+				if got, want := ind.IsFull(), i >= period; got != want {
+					t.Fatalf("after %d moves: %s.IsFull() = %v, want %v", i, name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMovingAverage_Resize(t *testing.T) {
+	tests := []struct {
+		name   string
+		period int
+		values []float64
+		resize int
+		want   movingList[float64]
+		avg    float64
+	}{
+		{
+			"empty",
+			3, nil, 5,
+			movingList[float64]{entries: make([]float64, 5)},
+			0,
+		},
+		{
+			"grow partial",
+			3, []float64{1, 2}, 4,
+			movingList[float64]{entries: []float64{1, 2, 0, 0}, pos: 2, count: 2},
+			1.5,
+		},
+		{
+			"grow wrapped",
+			3, []float64{1, 2, 3, 4}, 5,
+			movingList[float64]{entries: []float64{2, 3, 4, 0, 0}, pos: 3, count: 3},
+			3,
+		},
+		{
+			"shrink full",
+			4, []float64{1, 2, 3, 4}, 2,
+			movingList[float64]{entries: []float64{3, 4}, pos: 0, count: 2},
+			3.5,
+		},
+		{
+			"shrink wrapped",
+			4, []float64{1, 2, 3, 4, 5, 6}, 3,
+			movingList[float64]{entries: []float64{4, 5, 6}, pos: 0, count: 3},
+			5,
+		},
+		{
+			"shrink partial",
+			5, []float64{1, 2}, 3,
+			movingList[float64]{entries: []float64{1, 2, 0}, pos: 2, count: 2},
+			1.5,
+		},
+		{
+			"same",
+			3, []float64{1, 2, 3, 4}, 3,
+			movingList[float64]{entries: []float64{2, 3, 4}, pos: 0, count: 3},
+			3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ma := NewMovingAverage(tt.period)
+			for _, v := range tt.values {
+				ma.Move(v)
+			}
+
+			ma.Resize(tt.resize)
+			if !reflect.DeepEqual(ma.list, tt.want) {
+				t.Errorf("MovingAverage.Resize() list = %v, want %v", ma.list, tt.want)
+			}
+			if got := ma.Avg(); got != tt.avg {
+				t.Errorf("MovingAverage.Avg() = %v, want %v", got, tt.avg)
+			}
+			if got := ma.Len(); got != tt.resize {
+				t.Errorf("MovingAverage.Len() = %d, want %d", got, tt.resize)
+			}
+		})
+	}
+}
+
+func TestMovingAverage_Resize_move(t *testing.T) {
+	ma := NewMovingAverage(2)
+	ma.MoveAll([]float64{1, 2, 3})
+
+	ma.Resize(3)
+	if ma.IsFull() {
+		t.Fatal("MovingAverage.IsFull() after growing = true, want false")
+	}
+
+	ma.Move(4)
+	if got := ma.Avg(); got != 3 {
+		t.Errorf("MovingAverage.Avg() = %v, want 3", got)
+	}
+
+	ma.Move(5)
+	if got := ma.Avg(); got != 4 {
+		t.Errorf("MovingAverage.Avg() = %v, want 4", got)
+	}
+}
+
+func TestMovingAverage_Values(t *testing.T) {
+	tests := []struct {
+		name   string
+		period int
+		values []float64
+		want   []float64
+	}{
+		{"empty", 3, nil, []float64{}},
+		{"partial", 4, []float64{1, 2}, []float64{1, 2}},
+		{"full", 3, []float64{1, 2, 3}, []float64{1, 2, 3}},
+		{"wrapped", 3, []float64{1, 2, 3, 4, 5}, []float64{3, 4, 5}},
+		{"wrapped twice", 2, []float64{1, 2, 3, 4, 5}, []float64{4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ma := NewMovingAverage(tt.period)
+			for _, v := range tt.values {
+				ma.Move(v)
+			}
+
+			got := ma.Values()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MovingAverage.Values() = %v, want %v", got, tt.want)
+			}
+
+			if len(got) > 0 {
+				got[0] = -1
+				if ma.Values()[0] == -1 {
+					t.Error("MovingAverage.Values() did not return a copy")
+				}
+			}
+		})
+	}
+}
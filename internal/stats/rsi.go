@@ -0,0 +1,130 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+var _ MovingIndicator = (*RSI)(nil)
+
+// RSI is the Relative Strength Index over price changes:
+//
+//	rsi = 100 - 100/(1 + avgGain/avgLoss)
+//
+// The averages are seeded with the simple average of the first period changes,
+// after which Wilder's smoothing is applied:
+//
+//	avg = (avg*(period-1) + change) / period
+type RSI struct {
+	period  int
+	prev    float64 // last price
+	changes int     // price changes seen, up to period
+	gain    float64 // average gain, or the sum while seeding
+	loss    float64 // average loss, or the sum while seeding
+}
+
+// NewRSI returns an RSI over period price changes.
+// It needs period+1 prices before Value becomes valid.
+// It panics if period is not positive.
+func NewRSI(period int) *RSI {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return &RSI{period: period, changes: -1}
+}
+
+// Move the RSI with the closing price of a period.
+func (r *RSI) Move(price float64) {
+	if r.changes < 0 {
+		r.prev, r.changes = price, 0
+		return
+	}
+
+	var gain, loss float64
+	if change := price - r.prev; change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+	r.prev = price
+
+	p := float64(r.period)
+
+	switch {
+	case r.changes < r.period-1:
+		r.gain += gain
+		r.loss += loss
+		r.changes++
+	case r.changes == r.period-1:
+		r.gain = (r.gain + gain) / p
+		r.loss = (r.loss + loss) / p
+		r.changes++
+	default:
+		r.gain = (r.gain*(p-1) + gain) / p
+		r.loss = (r.loss*(p-1) + loss) / p
+	}
+}
+
+// Ready reports if enough prices have been moved in for a valid Value.
+func (r *RSI) Ready() bool {
+	return r.changes >= r.period
+}
+
+// Value returns the RSI in the range [0, 100].
+// It returns 0 until Ready and 50 when prices did not change at all.
+func (r *RSI) Value() float64 {
+	switch {
+	case !r.Ready():
+		return 0
+	case r.loss == 0 && r.gain == 0:
+		return 50
+	case r.loss == 0:
+		return 100
+	}
+
+	return 100 - 100/(1+r.gain/r.loss)
+}
+
+type rsiJSON struct {
+	Period  int     `json:"period"`
+	Prev    float64 `json:"prev"`
+	Changes int     `json:"changes"`
+	Gain    float64 `json:"gain"`
+	Loss    float64 `json:"loss"`
+}
+
+// MarshalJSON encodes the period and running averages,
+// so that they can be restored with UnmarshalJSON.
+func (r RSI) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rsiJSON{r.period, r.prev, r.changes, r.gain, r.loss})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (r *RSI) UnmarshalJSON(data []byte) error {
+	var v rsiJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.Period <= 0 || v.Changes < -1 || v.Changes > v.Period {
+		return errInvalidState
+	}
+
+	*r = RSI{v.Period, v.Prev, v.Changes, v.Gain, v.Loss}
+	return nil
+}
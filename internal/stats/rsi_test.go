@@ -0,0 +1,96 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNewRSI_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewRSI did not panic")
+		}
+	}()
+	NewRSI(0)
+}
+
+func TestRSI_Value(t *testing.T) {
+	tests := []struct {
+		name   string
+		period int
+		prices []float64
+		want   float64
+		ready  bool
+	}{
+		{"empty", 3, nil, 0, false},
+		{"not ready", 3, []float64{1, 2, 3}, 0, false},
+		// changes +1, +1, -1: gain 2/3, loss 1/3
+		{"seeded", 3, []float64{1, 2, 3, 2}, 100 - 100/(1+2.0), true},
+		// change +2: gain (2/3*2+2)/3 = 10/9, loss (1/3*2)/3 = 2/9
+		{"smoothed", 3, []float64{1, 2, 3, 2, 4}, 100 - 100/(1+5.0), true},
+		// change -3: gain (10/9*2)/3 = 20/27, loss (2/9*2+3)/3 = 31/27
+		{"smoothed loss", 3, []float64{1, 2, 3, 2, 4, 1}, 100 - 100/(1+20.0/31), true},
+		{"rising", 2, []float64{1, 2, 3, 4}, 100, true},
+		{"falling", 2, []float64{4, 3, 2, 1}, 0, true},
+		{"flat", 2, []float64{2, 2, 2}, 50, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRSI(tt.period)
+			for _, p := range tt.prices {
+				r.Move(p)
+			}
+
+			if got := r.Ready(); got != tt.ready {
+				t.Errorf("RSI.Ready() = %v, want %v", got, tt.ready)
+			}
+			if got := r.Value(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("RSI.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRSI_JSON(t *testing.T) {
+	r := NewRSI(3)
+	for _, p := range []float64{1, 2, 3, 2, 4} {
+		r.Move(p)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got RSI
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, r) {
+		t.Errorf("RSI JSON = %v, want %v", got, r)
+	}
+
+	if err = json.Unmarshal([]byte(`{"period":3,"changes":4}`), &got); err != errInvalidState {
+		t.Errorf("RSI.UnmarshalJSON() err = %v, want %v", err, errInvalidState)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "sync"
+
+var _ MovingIndicator = (*SafeMovingAverage)(nil)
+
+// SafeMovingAverage wraps a MovingAverage for concurrent use,
+// such as a market data goroutine moving values in
+// while a strategy goroutine reads the average.
+type SafeMovingAverage struct {
+	mtx sync.RWMutex
+	ma  MovingAverage
+}
+
+// NewSafeMovingAverage returns an empty SafeMovingAverage over period values.
+// It panics if period is not positive.
+func NewSafeMovingAverage(period int) *SafeMovingAverage {
+	return &SafeMovingAverage{ma: *NewMovingAverage(period)}
+}
+
+// Move is MovingAverage.Move.
+func (s *SafeMovingAverage) Move(value float64) {
+	s.mtx.Lock()
+	s.ma.Move(value)
+	s.mtx.Unlock()
+}
+
+// MoveAll is MovingAverage.MoveAll.
+func (s *SafeMovingAverage) MoveAll(values []float64) float64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.ma.MoveAll(values)
+}
+
+// Avg is MovingAverage.Avg.
+func (s *SafeMovingAverage) Avg() float64 {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.ma.Avg()
+}
+
+// Value returns Avg, implementing MovingIndicator.
+func (s *SafeMovingAverage) Value() float64 { return s.Avg() }
+
+// AvgIncl is MovingAverage.AvgIncl.
+func (s *SafeMovingAverage) AvgIncl(value, weight float64) float64 {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.ma.AvgIncl(value, weight)
+}
+
+// PeekIncl is MovingAverage.PeekIncl.
+func (s *SafeMovingAverage) PeekIncl(value float64) float64 {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.ma.PeekIncl(value)
+}
+
+// IsFull is MovingAverage.IsFull.
+func (s *SafeMovingAverage) IsFull() bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.ma.IsFull()
+}
@@ -0,0 +1,82 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSafeMovingAverage(t *testing.T) {
+	const (
+		writers = 4
+		readers = 4
+		moves   = 1000
+	)
+
+	s := NewSafeMovingAverage(10)
+	var wg sync.WaitGroup
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < moves; j++ {
+				s.Move(5)
+			}
+		}()
+	}
+
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < moves; j++ {
+				// Only 5 is moved in, so any consistent read is 5 or 0.
+				if avg := s.Avg(); avg != 0 && avg != 5 {
+					t.Errorf("SafeMovingAverage.Avg() = %v", avg)
+					return
+				}
+				if avg := s.AvgIncl(5, 0.5); avg != 5 {
+					t.Errorf("SafeMovingAverage.AvgIncl() = %v, want 5", avg)
+					return
+				}
+				s.PeekIncl(5)
+				s.IsFull()
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < moves/10; j++ {
+			s.MoveAll([]float64{5, 5, 5})
+		}
+	}()
+
+	wg.Wait()
+
+	if !s.IsFull() {
+		t.Error("SafeMovingAverage.IsFull() = false, want true")
+	}
+	if got := s.Value(); got != 5 {
+		t.Errorf("SafeMovingAverage.Value() = %v, want 5", got)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Sharpe calculates the annualized Sharpe ratio of a periodic return series:
+//
+//	(mean - riskFree) / stddev * sqrt(periodsPerYear)
+//
+// The mean and sample standard deviation are kept as running sums.
+type Sharpe struct {
+	riskFree       float64
+	periodsPerYear float64
+
+	n          int
+	sum, sumSq float64
+}
+
+// NewSharpe returns a Sharpe ratio calculator.
+// riskFree is the risk-free return per period.
+// periodsPerYear annualizes the ratio, for example 365 for daily crypto returns.
+func NewSharpe(riskFree, periodsPerYear float64) *Sharpe {
+	return &Sharpe{
+		riskFree:       riskFree,
+		periodsPerYear: periodsPerYear,
+	}
+}
+
+// Add the return of one period.
+func (s *Sharpe) Add(ret float64) {
+	s.n++
+	s.sum += ret
+	s.sumSq += ret * ret
+}
+
+// AddAll adds the returns of multiple periods and returns the resulting Value.
+// It is equivalent to calling Add for each return.
+func (s *Sharpe) AddAll(returns []float64) float64 {
+	for _, ret := range returns {
+		s.Add(ret)
+	}
+	return s.Value()
+}
+
+type sharpeJSON struct {
+	RiskFree       float64 `json:"riskFree"`
+	PeriodsPerYear float64 `json:"periodsPerYear"`
+	N              int     `json:"n"`
+	Sum            float64 `json:"sum"`
+	SumSq          float64 `json:"sumSq"`
+}
+
+// MarshalJSON encodes the parameters and running sums,
+// so that they can be restored with UnmarshalJSON.
+func (s Sharpe) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sharpeJSON{s.riskFree, s.periodsPerYear, s.n, s.sum, s.sumSq})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (s *Sharpe) UnmarshalJSON(data []byte) error {
+	var v sharpeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*s = Sharpe{v.RiskFree, v.PeriodsPerYear, v.N, v.Sum, v.SumSq}
+	return nil
+}
+
+// Value returns the annualized Sharpe ratio.
+// With less than 2 returns or zero standard deviation the ratio is undefined,
+// in which case 0 is returned.
+func (s *Sharpe) Value() float64 {
+	if s.n < 2 {
+		return 0
+	}
+
+	n := float64(s.n)
+	mean := s.sum / n
+	variance := (s.sumSq - s.sum*mean) / (n - 1)
+
+	if variance <= relEpsilon*s.sumSq {
+		return 0
+	}
+
+	return (mean - s.riskFree) / math.Sqrt(variance) * math.Sqrt(s.periodsPerYear)
+}
@@ -0,0 +1,128 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestSharpe_Value(t *testing.T) {
+	returns := []float64{0.01, 0.02, -0.01, 0.03}
+
+	// mean 0.0125, squared deviations sum to 0.000875
+	stddev := math.Sqrt(0.000875 / 3)
+
+	tests := []struct {
+		name     string
+		returns  []float64
+		riskFree float64
+		want     float64
+	}{
+		{
+			"empty",
+			nil,
+			0,
+			0,
+		},
+		{
+			"one",
+			returns[:1],
+			0,
+			0,
+		},
+		{
+			"zero stddev",
+			[]float64{0.01, 0.01, 0.01},
+			0,
+			0,
+		},
+		{
+			"no risk free",
+			returns,
+			0,
+			0.0125 / stddev * math.Sqrt(252),
+		},
+		{
+			"risk free",
+			returns,
+			0.005,
+			0.0075 / stddev * math.Sqrt(252),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSharpe(tt.riskFree, 252)
+			for _, r := range tt.returns {
+				s.Add(r)
+			}
+
+			if got := s.Value(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Sharpe.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSharpe_AddAll(t *testing.T) {
+	returns := testSeries(100, 0.2)
+
+	want := NewSharpe(0.01, 365)
+	for _, ret := range returns {
+		want.Add(ret)
+	}
+
+	if got := NewSharpe(0.01, 365).AddAll(returns); got != want.Value() {
+		t.Errorf("Sharpe.AddAll() = %v, want %v", got, want.Value())
+	}
+}
+
+func TestSharpe_JSON(t *testing.T) {
+	s := NewSharpe(0.01, 365)
+	s.AddAll(testSeries(20, 0.2))
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// non-addressable values must not lose state
+	value, err := json.Marshal(map[string]interface{}{"v": *s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"v":` + string(data) + `}`; string(value) != want {
+		t.Errorf("json.Marshal(value) = %s, want %s", value, want)
+	}
+
+	var got Sharpe
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ret := range testSeries(5, 0.6) {
+		s.Add(ret)
+		got.Add(ret)
+
+		if got.Value() != s.Value() {
+			t.Fatalf("restored Sharpe.Value() = %v, want %v", got.Value(), s.Value())
+		}
+	}
+}
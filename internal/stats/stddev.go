@@ -0,0 +1,134 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+)
+
+var _ MovingIndicator = (*MovingStdDev)(nil)
+
+// MovingStdDev is the rolling sample standard deviation
+// over the same kind of fixed window as MovingAverage.
+// Each Move is O(1), as a running sum and sum of squares are kept.
+type MovingStdDev struct {
+	list    movingList[float64]
+	total   float64 // running sum of the entries
+	squares float64 // running sum of the squared entries
+}
+
+// NewMovingStdDev returns an empty MovingStdDev over period values.
+// It panics if period is not positive.
+func NewMovingStdDev(period int) *MovingStdDev {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return &MovingStdDev{list: makeMovingList[float64](period)}
+}
+
+// Move the window by one value, replacing the oldest.
+func (sd *MovingStdDev) Move(value float64) {
+	if len(sd.list.entries) == 0 {
+		return
+	}
+
+	old := sd.list.move(value)
+	sd.total += value - old
+	sd.squares += value*value - old*old
+
+	if sd.list.pos == 0 {
+		sd.resum()
+	}
+}
+
+// MoveAll moves all values in, oldest first, and returns the resulting StdDev.
+// It is equivalent to calling Move for each value, apart from rounding.
+func (sd *MovingStdDev) MoveAll(values []float64) float64 {
+	sd.list.moveAll(values)
+	sd.resum()
+	return sd.StdDev()
+}
+
+// resum recalculates the running sums from the entries.
+func (sd *MovingStdDev) resum() {
+	sd.total, sd.squares = 0, 0
+	for _, v := range sd.list.entries {
+		sd.total += v
+		sd.squares += v * v
+	}
+}
+
+// MarshalJSON encodes the window, so that it can be restored with UnmarshalJSON.
+func (sd MovingStdDev) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sd.list)
+}
+
+// UnmarshalJSON restores a window encoded by MarshalJSON.
+func (sd *MovingStdDev) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &sd.list); err != nil {
+		return err
+	}
+
+	sd.resum()
+	return nil
+}
+
+// Values returns a copy of the values in the window, oldest first.
+func (sd MovingStdDev) Values() []float64 {
+	return sd.list.ordered()
+}
+
+// IsFull reports if period values have been moved in.
+func (sd MovingStdDev) IsFull() bool {
+	return sd.list.full()
+}
+
+// Mean returns the average of the values moved in so far.
+func (sd MovingStdDev) Mean() float64 {
+	if sd.list.count == 0 {
+		return 0
+	}
+
+	return sd.total / float64(sd.list.count)
+}
+
+// Variance returns the sample variance of the values moved in so far.
+// It returns 0 for less than 2 values.
+func (sd MovingStdDev) Variance() float64 {
+	n := float64(sd.list.count)
+	if n < 2 {
+		return 0
+	}
+
+	v := (sd.squares - sd.total*sd.total/n) / (n - 1)
+	if v < 0 { // cancellation for (nearly) constant values
+		return 0
+	}
+
+	return v
+}
+
+// StdDev returns the sample standard deviation, the square root of Variance.
+func (sd MovingStdDev) StdDev() float64 {
+	return math.Sqrt(sd.Variance())
+}
+
+// Value returns StdDev, implementing MovingIndicator.
+func (sd MovingStdDev) Value() float64 { return sd.StdDev() }
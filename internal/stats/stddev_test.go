@@ -0,0 +1,157 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// naiveVariance is the two-pass sample variance.
+func naiveVariance(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var ss float64
+	for _, v := range values {
+		ss += (v - mean) * (v - mean)
+	}
+
+	return ss / float64(len(values)-1)
+}
+
+func TestNewMovingStdDev_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewMovingStdDev did not panic")
+		}
+	}()
+	NewMovingStdDev(0)
+}
+
+func TestMovingStdDev_Variance(t *testing.T) {
+	tests := []struct {
+		name   string
+		period int
+		values []float64
+		want   float64
+	}{
+		{"empty", 3, nil, 0},
+		{"single", 3, []float64{5}, 0},
+		{"constant", 3, []float64{7, 7, 7, 7}, 0},
+		{"partial", 4, []float64{2, 4}, 2},
+		{"full", 4, []float64{2, 4, 4, 4, 5, 5, 7, 9}, 11.0 / 3},
+		{"moved", 3, []float64{100, 1, 2, 3}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sd := NewMovingStdDev(tt.period)
+			for _, v := range tt.values {
+				sd.Move(v)
+			}
+
+			if got := sd.Variance(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("MovingStdDev.Variance() = %v, want %v", got, tt.want)
+			}
+			if got := sd.StdDev(); math.Abs(got-math.Sqrt(tt.want)) > 1e-9 {
+				t.Errorf("MovingStdDev.StdDev() = %v, want %v", got, math.Sqrt(tt.want))
+			}
+		})
+	}
+}
+
+func TestMovingStdDev_naive(t *testing.T) {
+	for _, period := range []int{1, 2, 10, 50} {
+		t.Run(strconv.Itoa(period), func(t *testing.T) {
+			values := testSeries(1000, 0.7)
+			sd := NewMovingStdDev(period)
+
+			for i, v := range values {
+				sd.Move(v)
+
+				start := i + 1 - period
+				if start < 0 {
+					start = 0
+				}
+				window := values[start : i+1]
+
+				want := naiveVariance(window)
+				if got := sd.Variance(); math.Abs(got-want) > 1e-6 {
+					t.Fatalf("move %d: MovingStdDev.Variance() = %v, want %v", i, got, want)
+				}
+				if got, want := sd.Mean(), NewMovingAverageFrom(window).Avg(); math.Abs(got-want) > 1e-9 {
+					t.Fatalf("move %d: MovingStdDev.Mean() = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMovingStdDev_MoveAll(t *testing.T) {
+	for _, n := range []int{0, 5, 20, 1000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			values := testSeries(n, 0.3)
+
+			want := NewMovingStdDev(10)
+			for _, v := range values {
+				want.Move(v)
+			}
+
+			got := NewMovingStdDev(10)
+			if sd := got.MoveAll(values); math.Abs(sd-want.StdDev()) > 1e-9 {
+				t.Errorf("MovingStdDev.MoveAll() = %v, want %v", sd, want.StdDev())
+			}
+			if !reflect.DeepEqual(got.list, want.list) {
+				t.Errorf("MovingStdDev.MoveAll() = %v, want %v", got.list, want.list)
+			}
+		})
+	}
+}
+
+func TestMovingStdDev_JSON(t *testing.T) {
+	sd := NewMovingStdDev(5)
+	sd.MoveAll(testSeries(7, 0.5))
+
+	data, err := json.Marshal(sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got MovingStdDev
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, sd) {
+		t.Errorf("MovingStdDev JSON = %v, want %v", got, sd)
+	}
+
+	if err = json.Unmarshal([]byte(`{"entries":[1],"pos":3}`), &got); err != errInvalidState {
+		t.Errorf("MovingStdDev.UnmarshalJSON() err = %v, want %v", err, errInvalidState)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+// Stochastic is the stochastic oscillator.
+// %K is the position of the close in the range of the last kPeriod periods:
+//
+//	%K = 100 * (close - lowest low) / (highest high - lowest low)
+//
+// %D is the simple moving average of %K over dPeriod periods.
+type Stochastic struct {
+	low  MovingMin
+	high MovingMax
+	k    float64
+	d    MovingAverage
+}
+
+// NewStochastic returns a Stochastic oscillator, commonly over 14 and 3 periods.
+// It panics if a period is not positive.
+func NewStochastic(kPeriod, dPeriod int) *Stochastic {
+	return &Stochastic{
+		low:  *NewMovingMin(kPeriod),
+		high: *NewMovingMax(kPeriod),
+		d:    *NewMovingAverage(dPeriod),
+	}
+}
+
+// Move the oscillator with the high, low and close of a closed period.
+func (s *Stochastic) Move(high, low, close float64) {
+	s.high.Move(high)
+	s.low.Move(low)
+
+	hh, ll := s.high.Max(), s.low.Min()
+	if hh == ll {
+		// No range, the close is in the middle of it.
+		s.k = 50
+	} else {
+		s.k = 100 * (close - ll) / (hh - ll)
+	}
+
+	s.d.Move(s.k)
+}
+
+// K returns %K, in the range [0, 100].
+// When there is no range, for instance because all prices were equal,
+// it returns 50. Before the first Move it returns 0.
+func (s *Stochastic) K() float64 {
+	return s.k
+}
+
+// D returns %D, the moving average of %K.
+func (s *Stochastic) D() float64 {
+	return s.d.Avg()
+}
+
+// Value returns K.
+func (s *Stochastic) Value() float64 { return s.K() }
+
+type stochasticJSON struct {
+	Low  MovingMin     `json:"low"`
+	High MovingMax     `json:"high"`
+	K    float64       `json:"k"`
+	D    MovingAverage `json:"d"`
+}
+
+// MarshalJSON encodes the windows, so that they can be restored with UnmarshalJSON.
+func (s Stochastic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stochasticJSON{s.low, s.high, s.k, s.d})
+}
+
+// UnmarshalJSON restores the state encoded by MarshalJSON.
+func (s *Stochastic) UnmarshalJSON(data []byte) error {
+	var v stochasticJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*s = Stochastic{v.Low, v.High, v.K, v.D}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestStochastic(t *testing.T) {
+	type bar struct{ high, low, close float64 }
+
+	tests := []struct {
+		name string
+		bars []bar
+		k, d float64
+	}{
+		{"empty", nil, 0, 0},
+		{"close at high", []bar{{10, 5, 8}, {12, 6, 12}}, 100, (60 + 100) / 2.0},
+		{"close at low", []bar{{10, 5, 8}, {12, 4, 4}}, 0, (60 + 0) / 2.0},
+		{"no range", []bar{{5, 5, 5}, {5, 5, 5}}, 50, 50},
+		{"middle", []bar{{10, 0, 5}}, 50, 50},
+		// The high of 20 is evicted from the 3 period window.
+		{"evicted", []bar{{20, 10, 15}, {14, 10, 12}, {14, 11, 13}, {16, 12, 15}}, 5.0 / 6 * 100, (30 + 5.0/6*100) / 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStochastic(3, 2)
+			for _, b := range tt.bars {
+				s.Move(b.high, b.low, b.close)
+			}
+
+			if got := s.K(); math.Abs(got-tt.k) > 1e-9 {
+				t.Errorf("Stochastic.K() = %v, want %v", got, tt.k)
+			}
+			if got := s.D(); math.Abs(got-tt.d) > 1e-9 {
+				t.Errorf("Stochastic.D() = %v, want %v", got, tt.d)
+			}
+		})
+	}
+}
+
+func TestStochastic_saturates(t *testing.T) {
+	s := NewStochastic(5, 3)
+
+	// Every close at a new high.
+	for i := 1; i <= 10; i++ {
+		p := float64(i)
+		s.Move(p, p-1, p)
+	}
+	if s.K() != 100 || s.D() != 100 {
+		t.Errorf("rising: %%K = %v, %%D = %v, want 100", s.K(), s.D())
+	}
+
+	// Every close at a new low.
+	for i := 10; i > 0; i-- {
+		p := float64(i)
+		s.Move(p, p-1, p-1)
+	}
+	if s.K() != 0 || s.D() != 0 {
+		t.Errorf("falling: %%K = %v, %%D = %v, want 0", s.K(), s.D())
+	}
+}
+
+func TestStochastic_JSON(t *testing.T) {
+	s := NewStochastic(3, 2)
+	s.Move(20, 10, 15)
+	s.Move(14, 10, 12)
+	s.Move(14, 11, 13)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Stochastic
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Move(16, 12, 15)
+	got.Move(16, 12, 15)
+	if got.K() != s.K() || got.D() != s.D() {
+		t.Errorf("restored Stochastic = %v, %v, want %v, %v", got.K(), got.D(), s.K(), s.D())
+	}
+	if !reflect.DeepEqual(got.d, s.d) {
+		t.Errorf("restored Stochastic %%D = %v, want %v", got.d, s.d)
+	}
+}
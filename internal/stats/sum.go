@@ -0,0 +1,101 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Number is the constraint of integer and floating point types.
+type Number interface {
+	constraints.Integer | constraints.Float
+}
+
+// MovingSum is the running sum over a window of values.
+// With integer types, such as trade counts, the sum is exact.
+type MovingSum[T Number] struct {
+	list movingList[T]
+	sum  T
+}
+
+// NewMovingSum returns an empty MovingSum over period values.
+// It panics if period is not positive.
+func NewMovingSum[T Number](period int) *MovingSum[T] {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return &MovingSum[T]{list: makeMovingList[T](period)}
+}
+
+// Move the window by one value, replacing the oldest.
+func (s *MovingSum[T]) Move(value T) {
+	if len(s.list.entries) == 0 {
+		return
+	}
+
+	// Subtracting first keeps unsigned sums from wrapping below zero.
+	s.sum -= s.list.move(value)
+	s.sum += value
+
+	if s.list.pos == 0 {
+		s.resum()
+	}
+}
+
+// MoveAll moves all values in, oldest first, and returns the resulting Sum.
+func (s *MovingSum[T]) MoveAll(values []T) T {
+	s.list.moveAll(values)
+	s.resum()
+	return s.sum
+}
+
+// resum recalculates the sum from the entries.
+func (s *MovingSum[T]) resum() {
+	s.sum = 0
+	for _, v := range s.list.entries {
+		s.sum += v
+	}
+}
+
+// Sum returns the sum of the values in the window.
+func (s *MovingSum[T]) Sum() T {
+	return s.sum
+}
+
+// IsFull reports if period values have been moved in.
+func (s *MovingSum[T]) IsFull() bool {
+	return s.list.full()
+}
+
+// MarshalJSON encodes the window, so that it can be restored with UnmarshalJSON.
+func (s MovingSum[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.list)
+}
+
+// UnmarshalJSON restores a window encoded by MarshalJSON.
+func (s *MovingSum[T]) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.list); err != nil {
+		return err
+	}
+
+	s.resum()
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMovingSum_int(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int
+		want   int
+		full   bool
+	}{
+		{"empty", nil, 0, false},
+		{"partial", []int{3, 4}, 7, false},
+		{"full", []int{3, 4, 5}, 12, true},
+		{"evicted", []int{100, 3, 4, 5}, 12, true},
+		{"negative", []int{1, -4, 2, -7}, -9, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMovingSum[int](3)
+			for _, v := range tt.values {
+				s.Move(v)
+			}
+			if got := s.Sum(); got != tt.want {
+				t.Errorf("MovingSum.Sum() = %v, want %v", got, tt.want)
+			}
+			if got := s.IsFull(); got != tt.full {
+				t.Errorf("MovingSum.IsFull() = %v, want %v", got, tt.full)
+			}
+
+			s = NewMovingSum[int](3)
+			if got := s.MoveAll(tt.values); got != tt.want {
+				t.Errorf("MovingSum.MoveAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMovingSum_uint(t *testing.T) {
+	s := NewMovingSum[uint64](2)
+	for _, v := range []uint64{10, 1, 2, 3} {
+		s.Move(v)
+	}
+	if got := s.Sum(); got != 5 {
+		t.Errorf("MovingSum.Sum() = %v, want 5", got)
+	}
+}
+
+func TestMovingSum_float64(t *testing.T) {
+	values := testSeries(100, 0.6)
+	s := NewMovingSum[float64](7)
+
+	for i, v := range values {
+		s.Move(v)
+
+		start := i - 6
+		if start < 0 {
+			start = 0
+		}
+		var want float64
+		for _, w := range values[start : i+1] {
+			want += w
+		}
+
+		if got := s.Sum(); math.Abs(got-want) > 1e-9 {
+			t.Fatalf("move %d: MovingSum.Sum() = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestMovingSum_JSON(t *testing.T) {
+	s := NewMovingSum[int](3)
+	s.MoveAll([]int{1, 2, 3, 4})
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got MovingSum[int]
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, s) {
+		t.Errorf("MovingSum JSON = %v, want %v", got, s)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+// VWAP is the volume weighted average price over a window of trades or klines:
+// sum(price*volume) / sum(volume).
+// Each Move is O(1), as running sums are kept.
+type VWAP struct {
+	list   movingList[pair] // x is price, y is volume
+	value  float64          // running sum of price*volume
+	volume float64          // running sum of volume
+}
+
+// NewVWAP returns an empty VWAP over window prices.
+// It panics if window is not positive.
+func NewVWAP(window int) *VWAP {
+	if window <= 0 {
+		panic("stats: window must be positive")
+	}
+	return &VWAP{list: makeMovingList[pair](window)}
+}
+
+// Move the window by one price and its volume, replacing the oldest.
+func (v *VWAP) Move(price, volume float64) {
+	if len(v.list.entries) == 0 {
+		return
+	}
+
+	old := v.list.move(pair{price, volume})
+	v.value += price*volume - old.x*old.y
+	v.volume += volume - old.y
+
+	if v.list.pos == 0 {
+		v.resum()
+	}
+}
+
+// resum recalculates the running sums from the entries.
+func (v *VWAP) resum() {
+	v.value, v.volume = 0, 0
+	for _, p := range v.list.entries {
+		v.value += p.x * p.y
+		v.volume += p.y
+	}
+}
+
+// MarshalJSON encodes the window, so that it can be restored with UnmarshalJSON.
+func (v VWAP) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.list)
+}
+
+// UnmarshalJSON restores a window encoded by MarshalJSON.
+func (v *VWAP) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &v.list); err != nil {
+		return err
+	}
+
+	v.resum()
+	return nil
+}
+
+// Volume returns the total volume in the window.
+func (v *VWAP) Volume() float64 {
+	return v.volume
+}
+
+// Value returns the volume weighted average price.
+// When there is no volume in the window, VWAP is undefined
+// and 0 is returned.
+func (v *VWAP) Value() float64 {
+	if v.volume <= 0 {
+		return 0
+	}
+
+	return v.value / v.volume
+}
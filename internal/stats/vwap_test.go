@@ -0,0 +1,89 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNewVWAP_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewVWAP did not panic")
+		}
+	}()
+	NewVWAP(0)
+}
+
+func TestVWAP_Value(t *testing.T) {
+	type trade struct{ price, volume float64 }
+
+	tests := []struct {
+		name   string
+		window int
+		trades []trade
+		want   float64
+	}{
+		{"empty", 3, nil, 0},
+		{"single", 3, []trade{{10, 2}}, 10},
+		{"mixed", 3, []trade{{10, 1}, {20, 3}}, (10 + 60) / 4.0},
+		{"full", 3, []trade{{10, 1}, {20, 3}, {30, 6}}, (10 + 60 + 180) / 10.0},
+		{"evicted", 3, []trade{{100, 50}, {10, 1}, {20, 3}, {30, 6}}, (10 + 60 + 180) / 10.0},
+		{"zero volume", 2, []trade{{10, 1}, {20, 0}, {30, 0}}, 0},
+		{"some zero volume", 3, []trade{{10, 0}, {20, 2}, {30, 0}}, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVWAP(tt.window)
+			for _, tr := range tt.trades {
+				v.Move(tr.price, tr.volume)
+			}
+
+			if got := v.Value(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("VWAP.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVWAP_JSON(t *testing.T) {
+	v := NewVWAP(3)
+	for i, p := range testSeries(5, 0.3) {
+		v.Move(p, float64(i+1))
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got VWAP
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.list, v.list) {
+		t.Errorf("VWAP JSON = %v, want %v", got.list, v.list)
+	}
+	if math.Abs(got.Value()-v.Value()) > 1e-9 {
+		t.Errorf("VWAP.Value() = %v, want %v", got.Value(), v.Value())
+	}
+}
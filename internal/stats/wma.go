@@ -0,0 +1,118 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import "encoding/json"
+
+var _ MovingIndicator = (*WMA)(nil)
+
+// WMA is a linearly weighted moving average.
+// The most recent value has weight N, the one before it N-1, and so on,
+// where N is the number of values in the window.
+type WMA struct {
+	list movingList[float64]
+}
+
+// NewWMA returns an empty WMA over period values.
+// It panics if period is not positive.
+func NewWMA(period int) *WMA {
+	if period <= 0 {
+		panic("stats: period must be positive")
+	}
+	return &WMA{list: makeMovingList[float64](period)}
+}
+
+// Move the window by one value, replacing the oldest.
+func (w *WMA) Move(value float64) {
+	w.list.move(value)
+}
+
+// MoveAll moves all values in, oldest first, and returns the resulting Avg.
+func (w *WMA) MoveAll(values []float64) float64 {
+	w.list.moveAll(values)
+	return w.Avg()
+}
+
+// MarshalJSON encodes the window, so that it can be restored with UnmarshalJSON.
+func (w WMA) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.list)
+}
+
+// UnmarshalJSON restores a window encoded by MarshalJSON.
+func (w *WMA) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &w.list)
+}
+
+// Values returns a copy of the values in the window, oldest first.
+func (w WMA) Values() []float64 {
+	return w.list.ordered()
+}
+
+// IsFull reports if period values have been moved in.
+func (w WMA) IsFull() bool {
+	return w.list.full()
+}
+
+// Avg returns the weighted average of the values moved in so far,
+// or 0 when there are none.
+func (w WMA) Avg() float64 {
+	sum, weights := w.weighted()
+	if weights == 0 {
+		return 0
+	}
+
+	return sum / weights
+}
+
+// AvgIncl calculates the weighted average with the additional value
+// as the newest entry, like MovingAverage.AvgIncl.
+// No value is evicted, so with weight 1.0 the value gets weight N+1.
+// A lower weight will influence the resulting average less.
+func (w WMA) AvgIncl(value, weight float64) float64 {
+	sum, weights := w.weighted()
+
+	weight *= float64(w.list.count + 1)
+	sum += value * weight
+	weights += weight
+
+	if weights == 0 {
+		return 0
+	}
+
+	return sum / weights
+}
+
+// weighted returns the sum of the linearly weighted values
+// and the sum of the weights.
+func (w WMA) weighted() (sum, weights float64) {
+	entries := w.list.entries
+	n := len(entries)
+
+	// Walk back from the newest entry, just before pos.
+	for i := 1; i <= w.list.count; i++ {
+		weight := float64(w.list.count - i + 1)
+		sum += weight * entries[(w.list.pos-i+n)%n]
+		weights += weight
+	}
+
+	return sum, weights
+}
+
+// Value returns Avg, implementing MovingIndicator.
+func (w WMA) Value() float64 { return w.Avg() }
@@ -0,0 +1,125 @@
+/*
+yatgo: Yet Another Trader in Go
+Copyright (C) 2022  Tim Möhlmann
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNewWMA_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewWMA did not panic")
+		}
+	}()
+	NewWMA(-1)
+}
+
+func TestWMA_Avg(t *testing.T) {
+	tests := []struct {
+		name   string
+		period int
+		values []float64
+		want   float64
+	}{
+		{"empty", 3, nil, 0},
+		{"single", 3, []float64{4}, 4},
+		{"partial", 3, []float64{1, 2}, (1*1 + 2*2) / 3.0},
+		{"full 3", 3, []float64{1, 2, 3}, (1*1 + 2*2 + 3*3) / 6.0},
+		{"wrapped 3", 3, []float64{1, 2, 3, 4}, (2*1 + 3*2 + 4*3) / 6.0},
+		{"wrapped twice 3", 3, []float64{9, 9, 9, 1, 2, 3, 4, 5}, (3*1 + 4*2 + 5*3) / 6.0},
+		{"full 4", 4, []float64{10, 20, 30, 40}, (10*1 + 20*2 + 30*3 + 40*4) / 10.0},
+		{"wrapped 4", 4, []float64{10, 20, 30, 40, 50, 60}, (30*1 + 40*2 + 50*3 + 60*4) / 10.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWMA(tt.period)
+			for _, v := range tt.values {
+				w.Move(v)
+			}
+			if got := w.Avg(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("WMA.Avg() = %v, want %v", got, tt.want)
+			}
+
+			w = NewWMA(tt.period)
+			if got := w.MoveAll(tt.values); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("WMA.MoveAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWMA_JSON(t *testing.T) {
+	w := NewWMA(4)
+	w.MoveAll([]float64{1, 2, 3, 4, 5, 6})
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got WMA
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, w) {
+		t.Errorf("WMA JSON = %v, want %v", got, w)
+	}
+}
+
+func TestWMA_AvgIncl(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		value  float64
+		weight float64
+		want   float64
+	}{
+		{"empty", nil, 4, 1, 4},
+		{"empty zero weight", nil, 4, 0, 0},
+		{"full weight", []float64{1, 2, 3}, 4, 1, 3},
+		{"half weight", []float64{1, 2, 3}, 4, 0.5, 2.75},
+		{"zero weight", []float64{1, 2, 3}, 4, 0, 14.0 / 6},
+		{"wrapped", []float64{9, 1, 2, 3}, 4, 1, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWMA(3)
+			w.MoveAll(tt.values)
+
+			if got := w.AvgIncl(tt.value, tt.weight); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("WMA.AvgIncl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ExampleWMA_AvgIncl() {
+	w := NewWMA(3)
+	w.MoveAll([]float64{1.0, 2.0, 3.0})
+	fmt.Println(w.AvgIncl(4.0, 1.0))
+	fmt.Println(w.AvgIncl(4.0, 0.5))
+
+	// Output: 3
+	// 2.75
+}